@@ -0,0 +1,4 @@
+// Package example 演示通过protoc-gen-transport根据proto文件生成客户端以及服务端代码
+package example
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --transport_out=. --transport_opt=paths=source_relative user.proto
@@ -0,0 +1,83 @@
+package example_test
+
+import (
+	"context"
+	"github.com/uzziahlin/transport/cmd/protoc-gen-transport/example"
+	"github.com/uzziahlin/transport/rpc"
+	"github.com/uzziahlin/transport/rpc/rpctest"
+	"github.com/uzziahlin/transport/rpc/status"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userServer struct {
+	name string
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *example.GetUserReq) (*example.GetUserResp, error) {
+	return &example.GetUserResp{Id: req.Id, Name: s.name}, nil
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *example.CreateUserReq) (*example.CreateUserResp, error) {
+	return &example.CreateUserResp{Id: "id-" + req.Name}, nil
+}
+
+func TestUserServiceClient(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("")
+	require.NoError(t, example.RegisterUserServiceServer(endpoint, &userServer{name: "default"}))
+	require.NoError(t, endpoint.Register(example.NewUserServiceService(&userServer{name: "v2"}, rpc.ServiceInfo{Version: "2.1.0", Group: "gray"})))
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	testCases := []struct {
+		name     string
+		client   *example.UserServiceClient
+		want     string
+		wantCode status.Code
+	}{
+		{
+			name:   "default",
+			client: &example.UserServiceClient{},
+			want:   "default",
+		},
+		{
+			name:   "version and group",
+			client: &example.UserServiceClient{Version: "^2.0.0", Group: "gray"},
+			want:   "v2",
+		},
+		{
+			name:     "version mismatch",
+			client:   &example.UserServiceClient{Version: "^3.0.0", Group: "gray"},
+			wantCode: status.NotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.NoError(t, server.NewConstructor().InitProxy(tc.client))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			resp, err := tc.client.GetUser(ctx, &example.GetUserReq{Id: "1"})
+			if tc.wantCode != status.OK {
+				assert.Equal(t, tc.wantCode, status.CodeOf(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, resp.Name)
+
+			created, err := tc.client.CreateUser(ctx, &example.CreateUserReq{Name: "Tom"})
+			require.NoError(t, err)
+			assert.Equal(t, "id-Tom", created.Id)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+// protoc-gen-transport 根据proto文件中的service生成服务端接口、注册函数以及客户端
+//
+// 安装:
+//
+//	go install github.com/uzziahlin/transport/cmd/protoc-gen-transport@latest
+//
+// 使用:
+//
+//	protoc --go_out=. --transport_out=. user_service.proto
+//
+// 生成的代码使用proto序列化协议，消息类型需要同时使用protoc-gen-go生成
+package main
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func main() {
+	protogen.Options{}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		for _, f := range gen.Files {
+			if !f.Generate {
+				continue
+			}
+			if _, err := generateFile(gen, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	contextPackage = protogen.GoImportPath("context")
+	rpcPackage     = protogen.GoImportPath("github.com/uzziahlin/transport/rpc")
+)
+
+// reservedNames 客户端结构体中已经使用的名字，rpc方法不能与其重名
+var reservedNames = map[string]bool{
+	"Addr":    true,
+	"Version": true,
+	"Group":   true,
+	"Info":    true,
+}
+
+// generateFile 为文件中的每个service生成代码，文件中没有service时不生成文件
+func generateFile(gen *protogen.Plugin, file *protogen.File) (*protogen.GeneratedFile, error) {
+	if len(file.Services) == 0 {
+		return nil, nil
+	}
+
+	for _, service := range file.Services {
+		if err := validateService(service); err != nil {
+			return nil, err
+		}
+	}
+
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_transport.pb.go", file.GoImportPath)
+
+	g.P("// Code generated by protoc-gen-transport. DO NOT EDIT.")
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+
+	for _, service := range file.Services {
+		generateService(g, service)
+	}
+
+	return g, nil
+}
+
+func validateService(service *protogen.Service) error {
+	for _, method := range service.Methods {
+		if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+			return fmt.Errorf("protoc-gen-transport: %s 不支持流式调用", method.Desc.FullName())
+		}
+		if reservedNames[method.GoName] {
+			return fmt.Errorf("protoc-gen-transport: %s 与生成的客户端中的 %s 重名", method.Desc.FullName(), method.GoName)
+		}
+	}
+	return nil
+}
+
+func generateService(g *protogen.GeneratedFile, service *protogen.Service) {
+	name := service.GoName
+	serverName := name + "Server"
+	clientName := name + "Client"
+	serviceNameConst := name + "ServiceName"
+	wrapperName := unexport(name) + "Service"
+
+	rpcIdent := func(ident string) string {
+		return g.QualifiedGoIdent(rpcPackage.Ident(ident))
+	}
+
+	g.P("// ", serviceNameConst, " ", name, "的服务名，客户端与服务端默认使用proto中的完整服务名")
+	g.P("const ", serviceNameConst, " = ", fmt.Sprintf("%q", service.Desc.FullName()))
+	g.P()
+
+	// 服务端接口
+	g.P("// ", serverName, " ", name, "的服务端接口")
+	if service.Comments.Leading != "" {
+		g.P("//")
+		g.P(strings.TrimSuffix(service.Comments.Leading.String(), "\n"))
+	}
+	if isDeprecated(service.Desc.Options()) {
+		g.P("//")
+		g.P(deprecationComment)
+	}
+	g.P("type ", serverName, " interface {")
+	for _, method := range service.Methods {
+		if c := methodComments(method); c != "" {
+			g.P(c)
+		}
+		g.P(method.GoName, signature(g, method))
+	}
+	g.P("}")
+	g.P()
+
+	// 注册函数
+	g.P("// Register", serverName, " 将srv注册到ep，服务名为", serviceNameConst)
+	g.P("func Register", serverName, "(ep *", rpcIdent("EndPoint"), ", srv ", serverName, ") error {")
+	g.P("return ep.Register(New", name, "Service(srv, ", rpcIdent("ServiceInfo"), "{}))")
+	g.P("}")
+	g.P()
+
+	g.P("// New", name, "Service 将srv包装为", rpcIdent("Service"), "，info可以指定版本以及分组，ServiceName为空时使用", serviceNameConst)
+	g.P("func New", name, "Service(srv ", serverName, ", info ", rpcIdent("ServiceInfo"), ") ", rpcIdent("Service"), " {")
+	g.P("if info.ServiceName == \"\" {")
+	g.P("info.ServiceName = ", serviceNameConst)
+	g.P("}")
+	g.P("return &", wrapperName, "{", serverName, ": srv, info: info}")
+	g.P("}")
+	g.P()
+
+	g.P("type ", wrapperName, " struct {")
+	g.P(serverName)
+	g.P("info ", rpcIdent("ServiceInfo"))
+	g.P("}")
+	g.P()
+
+	g.P("func (s *", wrapperName, ") Info() ", rpcIdent("ServiceInfo"), " {")
+	g.P("return s.info")
+	g.P("}")
+	g.P()
+
+	// 客户端
+	g.P("// ", clientName, " ", name, "的客户端，通过", rpcIdent("ProxyConstructor"), ".InitProxy初始化，固定使用proto序列化协议")
+	g.P("type ", clientName, " struct {")
+	g.P("// Addr 服务端地址")
+	g.P("Addr string")
+	g.P("// Version 期望的版本范围，为空时匹配任意版本")
+	g.P("Version string")
+	g.P("// Group 服务分组")
+	g.P("Group string")
+	for _, method := range service.Methods {
+		g.P()
+		if c := methodComments(method); c != "" {
+			g.P(c)
+		}
+		g.P(method.GoName, " func", signature(g, method), " `rpc:\"", methodTag(method), "\"`")
+	}
+	g.P("}")
+	g.P()
+
+	g.P("func (c *", clientName, ") Info() ", rpcIdent("ServiceInfo"), " {")
+	g.P("return ", rpcIdent("ServiceInfo"), "{")
+	g.P("ServiceName: ", serviceNameConst, ",")
+	g.P("Addr: c.Addr,")
+	g.P("Version: c.Version,")
+	g.P("Group: c.Group,")
+	g.P("}")
+	g.P("}")
+	g.P()
+}
+
+func signature(g *protogen.GeneratedFile, method *protogen.Method) string {
+	return fmt.Sprintf("(ctx %s, req *%s) (*%s, error)",
+		g.QualifiedGoIdent(contextPackage.Ident("Context")),
+		g.QualifiedGoIdent(method.Input.GoIdent),
+		g.QualifiedGoIdent(method.Output.GoIdent))
+}
+
+// methodTag 客户端函数属性的rpc tag，声明了没有副作用或者幂等的方法允许重试
+func methodTag(method *protogen.Method) string {
+	opts := []string{"serializer=proto"}
+
+	if o, ok := method.Desc.Options().(*descriptorpb.MethodOptions); ok {
+		switch o.GetIdempotencyLevel() {
+		case descriptorpb.MethodOptions_NO_SIDE_EFFECTS, descriptorpb.MethodOptions_IDEMPOTENT:
+			opts = append(opts, "idempotent")
+		}
+	}
+
+	return strings.Join(opts, ",")
+}
+
+const deprecationComment = "// Deprecated: Do not use."
+
+func methodComments(method *protogen.Method) string {
+	var sb strings.Builder
+
+	sb.WriteString(strings.TrimSuffix(method.Comments.Leading.String(), "\n"))
+
+	if isDeprecated(method.Desc.Options()) {
+		if sb.Len() > 0 {
+			sb.WriteString("\n//\n")
+		}
+		sb.WriteString(deprecationComment)
+	}
+
+	return sb.String()
+}
+
+func isDeprecated(opts interface{}) bool {
+	switch o := opts.(type) {
+	case *descriptorpb.ServiceOptions:
+		return o.GetDeprecated()
+	case *descriptorpb.MethodOptions:
+		return o.GetDeprecated()
+	}
+	return false
+}
+
+func unexport(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[n:]
+}
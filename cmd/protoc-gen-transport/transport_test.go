@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var update = flag.Bool("update", false, "更新testdata中的golden文件")
+
+func TestGenerateFile_Golden(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  string
+		golden string
+	}{
+		{
+			name:   "greeter",
+			input:  "greeter.pb.txt",
+			golden: "greeter_transport.pb.go.golden",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tc.input))
+			require.NoError(t, err)
+
+			set := &descriptorpb.FileDescriptorSet{}
+			require.NoError(t, prototext.Unmarshal(data, set))
+
+			resp := generate(t, set.File)
+			require.Empty(t, resp.GetError())
+			require.Len(t, resp.File, 1)
+
+			golden := filepath.Join("testdata", tc.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(golden, []byte(resp.File[0].GetContent()), 0644))
+			}
+
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, "example.com/greeter/greeter_transport.pb.go", resp.File[0].GetName())
+			assert.Equal(t, string(want), resp.File[0].GetContent())
+		})
+	}
+}
+
+func TestGenerateFile(t *testing.T) {
+	testCases := []struct {
+		name      string
+		file      *descriptorpb.FileDescriptorProto
+		wantFiles int
+		wantErr   string
+	}{
+		{
+			name:      "no service",
+			file:      testFile(),
+			wantFiles: 0,
+		},
+		{
+			name: "streaming",
+			file: testFile(&descriptorpb.MethodDescriptorProto{
+				Name:            proto.String("Watch"),
+				InputType:       proto.String(".test.Req"),
+				OutputType:      proto.String(".test.Resp"),
+				ServerStreaming: proto.Bool(true),
+			}),
+			wantErr: "protoc-gen-transport: test.TestService.Watch 不支持流式调用",
+		},
+		{
+			name: "reserved name",
+			file: testFile(&descriptorpb.MethodDescriptorProto{
+				Name:       proto.String("Info"),
+				InputType:  proto.String(".test.Req"),
+				OutputType: proto.String(".test.Resp"),
+			}),
+			wantErr: "protoc-gen-transport: test.TestService.Info 与生成的客户端中的 Info 重名",
+		},
+		{
+			name: "unary",
+			file: testFile(&descriptorpb.MethodDescriptorProto{
+				Name:       proto.String("Get"),
+				InputType:  proto.String(".test.Req"),
+				OutputType: proto.String(".test.Resp"),
+			}),
+			wantFiles: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := generate(t, []*descriptorpb.FileDescriptorProto{tc.file})
+			assert.Equal(t, tc.wantErr, resp.GetError())
+			assert.Len(t, resp.File, tc.wantFiles)
+		})
+	}
+}
+
+// generate 模拟protoc调用插件，最后一个文件为需要生成的文件
+func generate(t *testing.T, files []*descriptorpb.FileDescriptorProto) *pluginpb.CodeGeneratorResponse {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{files[len(files)-1].GetName()},
+		ProtoFile:      files,
+	}
+
+	gen, err := protogen.Options{}.New(req)
+	require.NoError(t, err)
+
+	for _, f := range gen.Files {
+		if !f.Generate {
+			continue
+		}
+		if _, err = generateFile(gen, f); err != nil {
+			gen.Error(err)
+		}
+	}
+
+	return gen.Response()
+}
+
+// testFile 包含消息Req、Resp的文件，methods不为空时生成服务TestService
+func testFile(methods ...*descriptorpb.MethodDescriptorProto) *descriptorpb.FileDescriptorProto {
+	f := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/test")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Req")},
+			{Name: proto.String("Resp")},
+		},
+	}
+
+	if len(methods) > 0 {
+		f.Service = []*descriptorpb.ServiceDescriptorProto{{
+			Name:   proto.String("TestService"),
+			Method: methods,
+		}}
+	}
+
+	return f
+}
@@ -0,0 +1,32 @@
+// Package example 演示通过transport-gen根据接口生成客户端以及服务端适配代码
+package example
+
+import "context"
+
+//go:generate go run github.com/uzziahlin/transport/cmd/transport-gen -type UserAPI
+
+// UserAPI 用户服务的接口，客户端与服务端共享
+type UserAPI interface {
+	// GetUser 根据id查询用户
+	//rpc:timeout=500ms,idempotent,retry=1
+	GetUser(ctx context.Context, req *GetUserReq) (*GetUserResp, error)
+	// CreateUser 创建用户
+	CreateUser(ctx context.Context, req *CreateUserReq) (*CreateUserResp, error)
+}
+
+type GetUserReq struct {
+	Id string
+}
+
+type GetUserResp struct {
+	Id   string
+	Name string
+}
+
+type CreateUserReq struct {
+	Name string
+}
+
+type CreateUserResp struct {
+	Id string
+}
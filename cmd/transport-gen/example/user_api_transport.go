@@ -0,0 +1,70 @@
+// Code generated by transport-gen. DO NOT EDIT.
+// source: user.go
+
+package example
+
+import (
+	context "context"
+	rpc "github.com/uzziahlin/transport/rpc"
+)
+
+// UserAPIServiceName UserAPI的默认服务名
+const UserAPIServiceName = "example.UserAPI"
+
+// RegisterUserAPIServer 将srv注册到ep，服务名为UserAPIServiceName
+func RegisterUserAPIServer(ep *rpc.EndPoint, srv UserAPI) error {
+	return ep.Register(NewUserAPIService(srv, rpc.ServiceInfo{}))
+}
+
+// NewUserAPIService 将srv包装为rpc.Service，info可以指定版本以及分组，ServiceName为空时使用UserAPIServiceName
+func NewUserAPIService(srv UserAPI, info rpc.ServiceInfo) rpc.Service {
+	if info.ServiceName == "" {
+		info.ServiceName = UserAPIServiceName
+	}
+	return &userAPIService{UserAPI: srv, info: info}
+}
+
+type userAPIService struct {
+	UserAPI
+	info rpc.ServiceInfo
+}
+
+func (s *userAPIService) Info() rpc.ServiceInfo {
+	return s.info
+}
+
+// UserAPIClient 通过rpc调用实现UserAPI，调用选项在创建时解析，调用时不使用反射
+type UserAPIClient struct {
+	getUserMethod    *rpc.MethodHandle[GetUserReq, GetUserResp]
+	createUserMethod *rpc.MethodHandle[CreateUserReq, CreateUserResp]
+}
+
+var _ UserAPI = (*UserAPIClient)(nil)
+
+// NewUserAPIClient 创建请求info.Addr的客户端，info.Version为期望的版本范围，ServiceName为空时使用UserAPIServiceName
+func NewUserAPIClient(c *rpc.ProxyConstructor, info rpc.ServiceInfo) (*UserAPIClient, error) {
+	if info.ServiceName == "" {
+		info.ServiceName = UserAPIServiceName
+	}
+
+	caller := c.NewCaller(info.Addr)
+	client := &UserAPIClient{}
+
+	var err error
+	if client.getUserMethod, err = rpc.NewServiceMethodHandle[GetUserReq, GetUserResp](caller, info, "GetUser", "timeout=500ms,idempotent,retry=1"); err != nil {
+		return nil, err
+	}
+	if client.createUserMethod, err = rpc.NewServiceMethodHandle[CreateUserReq, CreateUserResp](caller, info, "CreateUser", ""); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (c *UserAPIClient) GetUser(ctx context.Context, req *GetUserReq) (*GetUserResp, error) {
+	return c.getUserMethod.Call(ctx, req)
+}
+
+func (c *UserAPIClient) CreateUser(ctx context.Context, req *CreateUserReq) (*CreateUserResp, error) {
+	return c.createUserMethod.Call(ctx, req)
+}
@@ -0,0 +1,80 @@
+package example_test
+
+import (
+	"context"
+	"github.com/uzziahlin/transport/cmd/transport-gen/example"
+	"github.com/uzziahlin/transport/rpc"
+	"github.com/uzziahlin/transport/rpc/rpctest"
+	"github.com/uzziahlin/transport/rpc/status"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userServer struct{}
+
+func (s *userServer) GetUser(ctx context.Context, req *example.GetUserReq) (*example.GetUserResp, error) {
+	if req.Id == "slow" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &example.GetUserResp{Id: req.Id, Name: "Tom"}, nil
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *example.CreateUserReq) (*example.CreateUserResp, error) {
+	return &example.CreateUserResp{Id: "id-" + req.Name}, nil
+}
+
+func TestUserAPIClient(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("")
+	require.NoError(t, example.RegisterUserAPIServer(endpoint, &userServer{}))
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	var client example.UserAPI
+	client, err := example.NewUserAPIClient(server.NewConstructor(), rpc.ServiceInfo{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := client.GetUser(ctx, &example.GetUserReq{Id: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, &example.GetUserResp{Id: "1", Name: "Tom"}, user)
+
+	created, err := client.CreateUser(ctx, &example.CreateUserReq{Name: "Tom"})
+	require.NoError(t, err)
+	assert.Equal(t, "id-Tom", created.Id)
+
+	// 接口注释中声明了500ms的超时时间
+	start := time.Now()
+	_, err = client.GetUser(ctx, &example.GetUserReq{Id: "slow"})
+	assert.Equal(t, status.DeadlineExceeded, status.CodeOf(err))
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestNewUserAPIClient_Version(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("")
+	require.NoError(t, endpoint.Register(example.NewUserAPIService(&userServer{}, rpc.ServiceInfo{Version: "1.2.0"})))
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	_, err := example.NewUserAPIClient(server.NewConstructor(), rpc.ServiceInfo{Version: "not a range"})
+	assert.Error(t, err)
+
+	client, err := example.NewUserAPIClient(server.NewConstructor(), rpc.ServiceInfo{Version: "^1.0"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := client.GetUser(ctx, &example.GetUserReq{Id: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, "1", user.Id)
+}
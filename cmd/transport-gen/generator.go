@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const rpcPackage = "github.com/uzziahlin/transport/rpc"
+
+// directivePrefix 方法注释中调用选项的前缀
+const directivePrefix = "//rpc:"
+
+type config struct {
+	// dir 接口所在的目录
+	dir      string
+	typeName string
+	// service 服务名，为空时使用 包名.接口名
+	service string
+	// output 输出文件，相对路径相对于dir
+	output string
+}
+
+// iface 解析得到的接口
+type iface struct {
+	pkg     string
+	name    string
+	source  string
+	methods []*method
+	// imports 方法签名中引用的包，key为代码中使用的包名
+	imports map[string]string
+}
+
+type method struct {
+	name string
+	// ctxType、reqType、respType 为源码中的类型表达式，reqType和respType不包含指针
+	ctxType  string
+	reqType  string
+	respType string
+	// tag 方法注释中的调用选项
+	tag string
+}
+
+func run(cfg config) error {
+	if cfg.output == "" {
+		cfg.output = snakeCase(cfg.typeName) + "_transport.go"
+	}
+
+	output := cfg.output
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(cfg.dir, output)
+	}
+
+	src, err := generate(cfg.dir, cfg.typeName, cfg.service, filepath.Base(output))
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, src, 0644)
+}
+
+// generate 解析dir中名为typeName的接口并生成代码，解析时忽略测试文件以及输出文件
+func generate(dir, typeName, service, output string) ([]byte, error) {
+	it, err := parseInterface(dir, typeName, output)
+	if err != nil {
+		return nil, err
+	}
+
+	if service == "" {
+		service = it.pkg + "." + it.name
+	}
+
+	g := &generator{}
+	g.generate(it, service)
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化生成的代码失败, %w", err)
+	}
+
+	return src, nil
+}
+
+func parseInterface(dir, typeName, output string) (*iface, error) {
+	fset := token.NewFileSet()
+
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != output
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range pkgs {
+		for filename, file := range pkg.Files {
+			spec := findType(file, typeName)
+			if spec == nil {
+				continue
+			}
+
+			typ, ok := spec.Type.(*ast.InterfaceType)
+			if !ok {
+				return nil, fmt.Errorf("%s 不是接口", typeName)
+			}
+			if spec.TypeParams != nil {
+				return nil, fmt.Errorf("%s 不支持泛型接口", typeName)
+			}
+
+			p := &methodParser{
+				fset:        fset,
+				fileImports: fileImports(file),
+				imports:     map[string]string{},
+			}
+
+			it := &iface{
+				pkg:     file.Name.Name,
+				name:    typeName,
+				source:  filepath.Base(filename),
+				imports: p.imports,
+			}
+
+			for _, field := range typ.Methods.List {
+				m, err := p.parseMethod(field)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", fset.Position(field.Pos()), err)
+				}
+				it.methods = append(it.methods, m)
+			}
+
+			if len(it.methods) == 0 {
+				return nil, fmt.Errorf("%s 没有方法", typeName)
+			}
+
+			return it, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s 中没有找到类型 %s", dir, typeName)
+}
+
+func findType(file *ast.File, name string) *ast.TypeSpec {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if ts := spec.(*ast.TypeSpec); ts.Name.Name == name {
+				return ts
+			}
+		}
+	}
+	return nil
+}
+
+// fileImports 文件导入的包，key为代码中使用的包名
+func fileImports(file *ast.File) map[string]string {
+	res := make(map[string]string, len(file.Imports))
+	for _, spec := range file.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+		name := importName(p)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		res[name] = p
+	}
+	return res
+}
+
+var versionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// importName 推测没有指定别名时的包名，例如gopkg.in/yaml.v3的包名为yaml
+func importName(p string) string {
+	base := path.Base(p)
+	if versionSuffix.MatchString(base) && path.Dir(p) != "." {
+		base = path.Base(path.Dir(p))
+	}
+	if i := strings.Index(base, "."); i > 0 {
+		base = base[:i]
+	}
+	base = strings.TrimPrefix(base, "go-")
+	base = strings.TrimSuffix(base, "-go")
+	return base
+}
+
+// methodParser 解析接口的方法，并记录方法签名中引用的包
+type methodParser struct {
+	fset        *token.FileSet
+	fileImports map[string]string
+	imports     map[string]string
+}
+
+func (p *methodParser) parseMethod(field *ast.Field) (*method, error) {
+	if len(field.Names) == 0 {
+		return nil, fmt.Errorf("不支持嵌入接口 %s", p.expr(field.Type))
+	}
+
+	name := field.Names[0].Name
+	if !ast.IsExported(name) {
+		return nil, fmt.Errorf("方法 %s 必须是导出的", name)
+	}
+	if name == "Info" {
+		return nil, fmt.Errorf("方法 %s 与rpc.Service的Info方法重名", name)
+	}
+
+	fn := field.Type.(*ast.FuncType)
+	params := flatten(fn.Params)
+	results := flatten(fn.Results)
+
+	if len(params) != 2 || len(results) != 2 {
+		return nil, fmt.Errorf("方法 %s 必须形如 func(context.Context, *Req) (*Resp, error)", name)
+	}
+
+	ctx, ok := params[0].(*ast.SelectorExpr)
+	if !ok || ctx.Sel.Name != "Context" || p.packageOf(ctx) != "context" {
+		return nil, fmt.Errorf("方法 %s 的第一个参数必须是context.Context", name)
+	}
+
+	req, ok := params[1].(*ast.StarExpr)
+	if !ok {
+		return nil, fmt.Errorf("方法 %s 的第二个参数必须是指针", name)
+	}
+
+	resp, ok := results[0].(*ast.StarExpr)
+	if !ok {
+		return nil, fmt.Errorf("方法 %s 的第一个返回值必须是指针", name)
+	}
+
+	if errTyp, ok := results[1].(*ast.Ident); !ok || errTyp.Name != "error" {
+		return nil, fmt.Errorf("方法 %s 的第二个返回值必须是error", name)
+	}
+
+	for _, expr := range []ast.Expr{ctx, req.X, resp.X} {
+		if err := p.collectImports(expr); err != nil {
+			return nil, fmt.Errorf("方法 %s %w", name, err)
+		}
+	}
+
+	return &method{
+		name:     name,
+		ctxType:  p.expr(ctx),
+		reqType:  p.expr(req.X),
+		respType: p.expr(resp.X),
+		tag:      directives(field.Doc),
+	}, nil
+}
+
+// flatten 展开参数列表，func(a, b *Req)中的a和b各占一个位置
+func flatten(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+
+	var res []ast.Expr
+	for _, field := range fields.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			res = append(res, field.Type)
+		}
+	}
+	return res
+}
+
+func (p *methodParser) packageOf(sel *ast.SelectorExpr) string {
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	return p.fileImports[x.Name]
+}
+
+// collectImports 记录类型表达式中引用的包，生成的代码使用与源码相同的包名
+func (p *methodParser) collectImports(expr ast.Expr) error {
+	var err error
+
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || err != nil {
+			return err == nil
+		}
+
+		x, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		pkg, ok := p.fileImports[x.Name]
+		if !ok {
+			err = fmt.Errorf("引用的包 %s 不在导入列表中", x.Name)
+			return false
+		}
+		if x.Name == "rpc" && pkg != rpcPackage {
+			err = fmt.Errorf("引用的包 %s 与生成代码使用的rpc包重名", pkg)
+			return false
+		}
+
+		p.imports[x.Name] = pkg
+		return false
+	})
+
+	return err
+}
+
+func (p *methodParser) expr(expr ast.Expr) string {
+	var buf bytes.Buffer
+	_ = printer.Fprint(&buf, p.fset, expr)
+	return buf.String()
+}
+
+// directives 方法注释中的 //rpc:xxx 指令，多条指令使用逗号连接
+func directives(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+
+	var tags []string
+	for _, c := range doc.List {
+		if strings.HasPrefix(c.Text, directivePrefix) {
+			tags = append(tags, strings.TrimSpace(strings.TrimPrefix(c.Text, directivePrefix)))
+		}
+	}
+	return strings.Join(tags, ",")
+}
+
+type generator struct {
+	buf bytes.Buffer
+}
+
+// P 输出一行代码，与protogen.GeneratedFile.P相同
+func (g *generator) P(v ...interface{}) {
+	for _, x := range v {
+		fmt.Fprint(&g.buf, x)
+	}
+	fmt.Fprintln(&g.buf)
+}
+
+func (g *generator) generate(it *iface, service string) {
+	name := it.name
+	serviceNameConst := name + "ServiceName"
+	clientName := name + "Client"
+	wrapperName := unexport(name) + "Service"
+
+	g.P("// Code generated by transport-gen. DO NOT EDIT.")
+	g.P("// source: ", it.source)
+	g.P()
+	g.P("package ", it.pkg)
+	g.P()
+
+	imports := map[string]string{"rpc": rpcPackage}
+	for k, v := range it.imports {
+		imports[k] = v
+	}
+	names := make([]string, 0, len(imports))
+	for k := range imports {
+		names = append(names, k)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return imports[names[i]] < imports[names[j]]
+	})
+
+	g.P("import (")
+	for _, n := range names {
+		g.P(n, " ", strconv.Quote(imports[n]))
+	}
+	g.P(")")
+	g.P()
+
+	g.P("// ", serviceNameConst, " ", name, "的默认服务名")
+	g.P("const ", serviceNameConst, " = ", strconv.Quote(service))
+	g.P()
+
+	// 服务端适配
+	g.P("// Register", name, "Server 将srv注册到ep，服务名为", serviceNameConst)
+	g.P("func Register", name, "Server(ep *rpc.EndPoint, srv ", name, ") error {")
+	g.P("return ep.Register(New", name, "Service(srv, rpc.ServiceInfo{}))")
+	g.P("}")
+	g.P()
+
+	g.P("// New", name, "Service 将srv包装为rpc.Service，info可以指定版本以及分组，ServiceName为空时使用", serviceNameConst)
+	g.P("func New", name, "Service(srv ", name, ", info rpc.ServiceInfo) rpc.Service {")
+	g.P("if info.ServiceName == \"\" {")
+	g.P("info.ServiceName = ", serviceNameConst)
+	g.P("}")
+	g.P("return &", wrapperName, "{", name, ": srv, info: info}")
+	g.P("}")
+	g.P()
+
+	g.P("type ", wrapperName, " struct {")
+	g.P(name)
+	g.P("info rpc.ServiceInfo")
+	g.P("}")
+	g.P()
+
+	g.P("func (s *", wrapperName, ") Info() rpc.ServiceInfo {")
+	g.P("return s.info")
+	g.P("}")
+	g.P()
+
+	// 客户端
+	g.P("// ", clientName, " 通过rpc调用实现", name, "，调用选项在创建时解析，调用时不使用反射")
+	g.P("type ", clientName, " struct {")
+	for _, m := range it.methods {
+		g.P(handleName(m), " *rpc.MethodHandle[", m.reqType, ", ", m.respType, "]")
+	}
+	g.P("}")
+	g.P()
+
+	g.P("var _ ", name, " = (*", clientName, ")(nil)")
+	g.P()
+
+	g.P("// New", clientName, " 创建请求info.Addr的客户端，info.Version为期望的版本范围，ServiceName为空时使用", serviceNameConst)
+	g.P("func New", clientName, "(c *rpc.ProxyConstructor, info rpc.ServiceInfo) (*", clientName, ", error) {")
+	g.P("if info.ServiceName == \"\" {")
+	g.P("info.ServiceName = ", serviceNameConst)
+	g.P("}")
+	g.P()
+	g.P("caller := c.NewCaller(info.Addr)")
+	g.P("client := &", clientName, "{}")
+	g.P()
+	g.P("var err error")
+	for _, m := range it.methods {
+		g.P("if client.", handleName(m), ", err = rpc.NewServiceMethodHandle[", m.reqType, ", ", m.respType, "](caller, info, ",
+			strconv.Quote(m.name), ", ", strconv.Quote(m.tag), "); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+	}
+	g.P()
+	g.P("return client, nil")
+	g.P("}")
+	g.P()
+
+	for _, m := range it.methods {
+		g.P("func (c *", clientName, ") ", m.name, "(ctx ", m.ctxType, ", req *", m.reqType, ") (*", m.respType, ", error) {")
+		g.P("return c.", handleName(m), ".Call(ctx, req)")
+		g.P("}")
+		g.P()
+	}
+}
+
+// handleName 客户端中方法句柄的属性名，加上后缀避免与关键字冲突
+func handleName(m *method) string {
+	return unexport(m.name) + "Method"
+}
+
+func unexport(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[n:]
+}
+
+// snakeCase 将UserAPI转换为user_api
+func snakeCase(s string) string {
+	runes := []rune(s)
+
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// 单词的开头: 前一个字符是小写，或者是连续大写的最后一个
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "更新golden文件")
+
+func TestGenerate_Golden(t *testing.T) {
+	testCases := []struct {
+		name     string
+		dir      string
+		typeName string
+		golden   string
+	}{
+		{
+			// example中提交的代码需要与生成器的输出保持一致
+			name:     "example",
+			dir:      "example",
+			typeName: "UserAPI",
+			golden:   "user_api_transport.go",
+		},
+		{
+			name:     "alias",
+			dir:      filepath.Join("testdata", "alias"),
+			typeName: "AliasAPI",
+			golden:   "alias_api_transport.go.golden",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src, err := generate(tc.dir, tc.typeName, "", tc.golden)
+			require.NoError(t, err)
+
+			golden := filepath.Join(tc.dir, tc.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(golden, src, 0644))
+			}
+
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), string(src))
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name:    "not found",
+			src:     "type Other interface{}",
+			wantErr: "中没有找到类型 API",
+		},
+		{
+			name:    "not interface",
+			src:     "type API struct{}",
+			wantErr: "API 不是接口",
+		},
+		{
+			name:    "generic",
+			src:     "type API[T any] interface{ Get(ctx context.Context, req *T) (*T, error) }",
+			wantErr: "API 不支持泛型接口",
+		},
+		{
+			name:    "no method",
+			src:     "type API interface{}",
+			wantErr: "API 没有方法",
+		},
+		{
+			name:    "embedded",
+			src:     "type API interface{ fmt.Stringer }",
+			wantErr: "不支持嵌入接口 fmt.Stringer",
+		},
+		{
+			name:    "unexported",
+			src:     "type API interface{ get(ctx context.Context, req *Req) (*Resp, error) }",
+			wantErr: "方法 get 必须是导出的",
+		},
+		{
+			name:    "info",
+			src:     "type API interface{ Info(ctx context.Context, req *Req) (*Resp, error) }",
+			wantErr: "方法 Info 与rpc.Service的Info方法重名",
+		},
+		{
+			name:    "params",
+			src:     "type API interface{ Get(req *Req) (*Resp, error) }",
+			wantErr: "方法 Get 必须形如 func(context.Context, *Req) (*Resp, error)",
+		},
+		{
+			name:    "context",
+			src:     "type API interface{ Get(ctx fmt.Stringer, req *Req) (*Resp, error) }",
+			wantErr: "方法 Get 的第一个参数必须是context.Context",
+		},
+		{
+			name:    "request",
+			src:     "type API interface{ Get(ctx context.Context, req Req) (*Resp, error) }",
+			wantErr: "方法 Get 的第二个参数必须是指针",
+		},
+		{
+			name:    "response",
+			src:     "type API interface{ Get(ctx context.Context, req *Req) (Resp, error) }",
+			wantErr: "方法 Get 的第一个返回值必须是指针",
+		},
+		{
+			name:    "error",
+			src:     "type API interface{ Get(ctx context.Context, req *Req) (*Resp, fmt.Stringer) }",
+			wantErr: "方法 Get 的第二个返回值必须是error",
+		},
+		{
+			name:    "grouped params",
+			src:     "type API interface{ Get(ctx context.Context, a, b *Req) (*Resp, error) }",
+			wantErr: "方法 Get 必须形如 func(context.Context, *Req) (*Resp, error)",
+		},
+		{
+			name: "valid",
+			src:  "type API interface{ Get(ctx context.Context, req *Req) (*Resp, error) }",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := "package api\n\nimport (\n\t\"context\"\n\t\"fmt\"\n)\n\nvar _ context.Context\nvar _ fmt.Stringer\n\n" +
+				"type Req struct{}\n\ntype Resp struct{}\n\n" + tc.src + "\n"
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "api.go"), []byte(src), 0644))
+
+			_, err := generate(dir, "API", "", "api_transport.go")
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	src := "package api\n\nimport \"context\"\n\ntype Req struct{}\n\ntype Resp struct{}\n\n" +
+		"type UserAPI interface{ Get(ctx context.Context, req *Req) (*Resp, error) }\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api.go"), []byte(src), 0644))
+
+	require.NoError(t, run(config{dir: dir, typeName: "UserAPI", service: "user-service"}))
+
+	// 输出文件已经存在时重新生成不会解析输出文件本身
+	require.NoError(t, run(config{dir: dir, typeName: "UserAPI", service: "user-service"}))
+
+	data, err := os.ReadFile(filepath.Join(dir, "user_api_transport.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `const UserAPIServiceName = "user-service"`)
+}
+
+func TestImportName(t *testing.T) {
+	testCases := []struct {
+		path string
+		want string
+	}{
+		{path: "context", want: "context"},
+		{path: "github.com/uzziahlin/transport/rpc", want: "rpc"},
+		{path: "gopkg.in/yaml.v3", want: "yaml"},
+		{path: "github.com/go-redis/redis/v8", want: "redis"},
+		{path: "github.com/mattn/go-sqlite3", want: "sqlite3"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			assert.Equal(t, tc.want, importName(tc.path))
+		})
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{name: "UserAPI", want: "user_api"},
+		{name: "UserService", want: "user_service"},
+		{name: "HTTPServer", want: "http_server"},
+		{name: "api", want: "api"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, snakeCase(tc.name))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// transport-gen 根据Go接口生成rpc客户端以及服务端适配代码，通常通过go:generate调用:
+//
+//	//go:generate go run github.com/uzziahlin/transport/cmd/transport-gen -type UserAPI
+//
+// 接口的方法必须形如 Method(ctx context.Context, req *Req) (*Resp, error)。
+// 方法注释中的 //rpc:xxx 指令与InitProxy中函数属性的rpc tag格式相同，例如
+//
+//	// GetUser 根据id查询用户
+//	//rpc:timeout=500ms,idempotent
+//	GetUser(ctx context.Context, req *GetUserReq) (*GetUserResp, error)
+//
+// 生成的文件包含:
+//
+//	XxxClient      实现接口的客户端，调用选项在创建时解析，调用时不使用反射
+//	RegisterXxxServer、NewXxxService  将接口的任意实现注册到EndPoint
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	cfg := config{}
+
+	flag.StringVar(&cfg.typeName, "type", "", "接口名，必填")
+	flag.StringVar(&cfg.service, "service", "", "服务名，默认为 包名.接口名")
+	flag.StringVar(&cfg.output, "output", "", "输出文件，默认为 <接口名的蛇形命名>_transport.go")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: transport-gen -type Interface [-service name] [-output file] [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("transport-gen: ")
+
+	if cfg.typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg.dir = "."
+	if flag.NArg() > 0 {
+		cfg.dir = flag.Arg(0)
+	}
+
+	if err := run(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,25 @@
+package alias
+
+import (
+	stdctx "context"
+
+	"github.com/uzziahlin/transport/rpc/proto/user_service/gen"
+)
+
+// AliasAPI 使用了包别名以及其他包中的类型
+type AliasAPI interface {
+	//rpc:serializer=proto
+	GetById(ctx stdctx.Context, req *gen.UserReq) (*gen.UserResp, error)
+	// Batch 参数没有名字，调用选项分为多条指令
+	//rpc:timeout=1s
+	//rpc:compressor=gzip
+	Batch(stdctx.Context, *BatchReq) (*BatchResp, error)
+}
+
+type BatchReq struct {
+	Ids []string
+}
+
+type BatchResp struct {
+	Users []*gen.UserResp
+}
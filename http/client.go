@@ -2,15 +2,18 @@ package http
 
 import (
 	"context"
+	"github.com/uzziahlin/transport/logger"
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 func NewDefaultClient(opts ...ClientOption) Client {
 
 	c := &DefaultClient{
 		client: &http.Client{},
+		logger: logger.Default(),
 	}
 
 	for _, opt := range opts {
@@ -34,9 +37,18 @@ func WithProxy(proxyUrl *url.URL) ClientOption {
 	}
 }
 
+// WithLogger 设置logger，默认为logger.Default()
+// 请求失败的错误会返回给调用方，所以只输出Debug级别的日志
+func WithLogger(l logger.Logger) ClientOption {
+	return func(c *DefaultClient) {
+		c.logger = l
+	}
+}
+
 type DefaultClient struct {
 	client   *http.Client
 	proxyUrl *url.URL
+	logger   logger.Logger
 }
 
 func (c DefaultClient) Get(ctx context.Context, url string) (*Response, error) {
@@ -69,12 +81,19 @@ func (c DefaultClient) Send(ctx context.Context, req *Request) (*Response, error
 
 	request.Header = http.Header(req.Header)
 
+	start := time.Now()
+
 	resp, err := c.client.Do(request)
 
 	if err != nil {
+		c.logger.Debug("http请求失败", "http_method", req.Method, "url", req.Url,
+			logger.KeyLatency, time.Since(start), logger.KeyError, err)
 		return nil, err
 	}
 
+	c.logger.Debug("http请求完成", "http_method", req.Method, "url", req.Url,
+		"status", resp.StatusCode, logger.KeyLatency, time.Since(start))
+
 	return &Response{
 		StatusCode: resp.StatusCode,
 		Header:     Header(resp.Header),
@@ -0,0 +1,161 @@
+// Package logger 定义了结构化日志接口，http客户端以及rpc框架都通过该接口输出日志
+package logger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+type Level int8
+
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", l)
+	}
+}
+
+// 框架输出日志时使用的字段名
+const (
+	KeyService   = "service"
+	KeyMethod    = "method"
+	KeyMessageID = "message_id"
+	KeyPeer      = "peer"
+	KeyError     = "error"
+	KeyLatency   = "latency"
+	KeyPanic     = "panic"
+	KeyStack     = "stack"
+)
+
+// Logger 结构化日志接口，kv为k1, v1, k2, v2...形式的字段
+type Logger interface {
+	Debug(msg string, kv ...any)
+	Info(msg string, kv ...any)
+	Warn(msg string, kv ...any)
+	Error(msg string, kv ...any)
+	// With 返回一个在每条日志中都携带kv字段的Logger
+	With(kv ...any) Logger
+}
+
+// Default 默认的Logger，使用标准库的log.Default()输出Info及以上级别的日志
+func Default() Logger {
+	return NewStdLogger(log.Default(), LevelInfo)
+}
+
+// NewStdLogger 基于标准库log.Logger的实现，日志形如 [INFO] msg k1=v1 k2=v2
+// 低于level级别的日志会被丢弃
+func NewStdLogger(l *log.Logger, level Level) Logger {
+	return &stdLogger{
+		l:     l,
+		level: level,
+	}
+}
+
+type stdLogger struct {
+	l      *log.Logger
+	level  Level
+	fields []any
+}
+
+func (s *stdLogger) Debug(msg string, kv ...any) {
+	s.log(LevelDebug, msg, kv)
+}
+
+func (s *stdLogger) Info(msg string, kv ...any) {
+	s.log(LevelInfo, msg, kv)
+}
+
+func (s *stdLogger) Warn(msg string, kv ...any) {
+	s.log(LevelWarn, msg, kv)
+}
+
+func (s *stdLogger) Error(msg string, kv ...any) {
+	s.log(LevelError, msg, kv)
+}
+
+func (s *stdLogger) With(kv ...any) Logger {
+	fields := make([]any, 0, len(s.fields)+len(kv))
+	fields = append(fields, s.fields...)
+	fields = append(fields, kv...)
+
+	return &stdLogger{
+		l:      s.l,
+		level:  s.level,
+		fields: fields,
+	}
+}
+
+func (s *stdLogger) log(level Level, msg string, kv []any) {
+	if level < s.level {
+		return
+	}
+
+	sb := strings.Builder{}
+
+	sb.WriteString("[")
+	sb.WriteString(level.String())
+	sb.WriteString("] ")
+	sb.WriteString(msg)
+
+	writeFields(&sb, s.fields)
+	writeFields(&sb, kv)
+
+	_ = s.l.Output(3, sb.String())
+}
+
+// writeFields 以k=v的形式输出字段，值包含空白字符时加上引号，落单的key使用!BADKEY作为值
+func writeFields(sb *strings.Builder, kv []any) {
+	for i := 0; i < len(kv); i += 2 {
+		sb.WriteByte(' ')
+		sb.WriteString(fmt.Sprint(kv[i]))
+		sb.WriteByte('=')
+
+		if i+1 >= len(kv) {
+			sb.WriteString("!BADKEY")
+			continue
+		}
+
+		val := fmt.Sprint(kv[i+1])
+
+		if val == "" || strings.ContainsAny(val, " \t\n\"=") {
+			val = fmt.Sprintf("%q", val)
+		}
+
+		sb.WriteString(val)
+	}
+}
+
+// NewNopLogger 丢弃所有日志
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (n nopLogger) Debug(msg string, kv ...any) {}
+
+func (n nopLogger) Info(msg string, kv ...any) {}
+
+func (n nopLogger) Warn(msg string, kv ...any) {}
+
+func (n nopLogger) Error(msg string, kv ...any) {}
+
+func (n nopLogger) With(kv ...any) Logger {
+	return n
+}
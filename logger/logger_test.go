@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	l := NewStdLogger(log.New(buf, "", 0), LevelInfo).With(KeyService, "user-service")
+
+	l.Debug("dropped")
+	l.Info("请求处理完成", KeyMethod, "GetById", KeyMessageID, 12)
+	l.Error("请求处理失败", KeyError, errors.New("this is the err"), "dangling")
+
+	assert.Equal(t, `[INFO] 请求处理完成 service=user-service method=GetById message_id=12
+[ERROR] 请求处理失败 service=user-service error="this is the err" dangling=!BADKEY
+`, buf.String())
+}
@@ -0,0 +1,41 @@
+//go:build go1.21
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger 基于log/slog的实现，日志级别以及输出格式由handler决定
+func NewSlogLogger(handler slog.Handler) Logger {
+	return &slogLogger{
+		l: slog.New(handler),
+	}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s *slogLogger) Debug(msg string, kv ...any) {
+	s.l.Log(context.Background(), slog.LevelDebug, msg, kv...)
+}
+
+func (s *slogLogger) Info(msg string, kv ...any) {
+	s.l.Log(context.Background(), slog.LevelInfo, msg, kv...)
+}
+
+func (s *slogLogger) Warn(msg string, kv ...any) {
+	s.l.Log(context.Background(), slog.LevelWarn, msg, kv...)
+}
+
+func (s *slogLogger) Error(msg string, kv ...any) {
+	s.l.Log(context.Background(), slog.LevelError, msg, kv...)
+}
+
+func (s *slogLogger) With(kv ...any) Logger {
+	return &slogLogger{
+		l: s.l.With(kv...),
+	}
+}
@@ -0,0 +1,32 @@
+//go:build go1.21
+
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	h := slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+
+	l := NewSlogLogger(h).With(KeyService, "user-service")
+
+	l.Debug("dropped")
+	l.Warn("slow call", KeyMethod, "GetById")
+
+	assert.Equal(t, "level=WARN msg=\"slow call\" service=user-service method=GetById\n", buf.String())
+}
@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"context"
+	"github.com/uzziahlin/transport/rpc/auth"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/metadata"
+)
+
+// WithCredentials 客户端每次调用前通过c获取凭证，并附加到请求的元数据中
+func WithCredentials(c auth.Credentials) ConstructorOpt {
+	return func(pc *ProxyConstructor) {
+		pc.credentials = c
+	}
+}
+
+// WithAuthenticator 服务端在调用服务方法前认证调用方，认证失败返回status.Unauthenticated
+// 认证通过的调用方可以在服务方法中通过auth.FromContext获取
+func WithAuthenticator(a auth.Authenticator) EndPointOpt {
+	return func(e *EndPoint) {
+		e.authenticator = a
+	}
+}
+
+// WithAuthorization 服务端根据授权策略判断调用方能否调用方法，没有权限时返回status.PermissionDenied
+// 需要配合WithAuthenticator使用，没有认证的调用方会被拒绝
+func WithAuthorization(p *auth.Policy) EndPointOpt {
+	return func(e *EndPoint) {
+		e.policy = p
+	}
+}
+
+// authorize 认证调用方并检查调用权限，认证通过时将调用方放入ctx
+func (e *EndPoint) authorize(ctx context.Context, req *message.Request) (context.Context, error) {
+	var principal *auth.Principal
+
+	if e.authenticator != nil {
+		p, err := e.authenticator.Authenticate(ctx, req)
+		if err != nil {
+			return ctx, err
+		}
+		principal = p
+		ctx = auth.NewContext(ctx, p)
+	}
+
+	if e.policy != nil {
+		if err := e.policy.Authorize(principal, req.ServiceName, req.MethodName); err != nil {
+			return ctx, err
+		}
+	}
+
+	return ctx, nil
+}
+
+// appendCredentials 将凭证附加到请求的元数据中，凭证不能使用框架保留的key
+func (p *ProxyConstructor) appendCredentials(ctx context.Context, req *message.Request) error {
+	if p.credentials == nil {
+		return nil
+	}
+
+	md, err := p.credentials.Metadata(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range md {
+		if err = metadata.Validate(k, v); err != nil {
+			return err
+		}
+		req.Meta[k] = v
+	}
+
+	return nil
+}
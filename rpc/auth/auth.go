@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/status"
+)
+
+// 凭证在请求元数据中使用的key
+const (
+	AuthorizationKey      = "authorization"
+	APIKeyKey             = "x-api-key"
+	SignatureKey          = "x-signature"
+	SignatureKeyIDKey     = "x-signature-key"
+	SignatureTimestampKey = "x-signature-timestamp"
+	SignatureNonceKey     = "x-signature-nonce"
+)
+
+// Principal 认证通过的调用方
+type Principal struct {
+	Name  string
+	Roles []string
+}
+
+// HasRole 判断调用方是否拥有role
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext 将调用方放入ctx，服务方法可以通过FromContext获取
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext 获取认证通过的调用方
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// ErrNoCredentials 请求中没有当前Authenticator支持的凭证，Chain会继续尝试下一个Authenticator
+var ErrNoCredentials = status.New(status.Unauthenticated, "micro：请求中没有凭证")
+
+// Authenticator 服务端的认证器，根据请求的元数据认证调用方
+// 认证失败时返回status.Unauthenticated错误，请求中没有对应的凭证时返回ErrNoCredentials
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *message.Request) (*Principal, error)
+}
+
+// AuthenticatorFunc 函数形式的Authenticator
+type AuthenticatorFunc func(ctx context.Context, req *message.Request) (*Principal, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, req *message.Request) (*Principal, error) {
+	return f(ctx, req)
+}
+
+// Chain 依次尝试多个Authenticator，使用第一个找到了凭证的Authenticator的结果
+func Chain(authenticators ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, req *message.Request) (*Principal, error) {
+		for _, a := range authenticators {
+			p, err := a.Authenticate(ctx, req)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			return p, err
+		}
+		return nil, ErrNoCredentials
+	})
+}
+
+func unauthenticated(msg string) error {
+	return status.New(status.Unauthenticated, msg)
+}
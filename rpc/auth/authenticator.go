@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/uzziahlin/transport/rpc/message"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const bearerPrefix = "Bearer "
+
+// Bearer 认证Authorization元数据中的bearer token，verify负责校验token并返回对应的调用方
+func Bearer(verify func(ctx context.Context, token string) (*Principal, error)) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, req *message.Request) (*Principal, error) {
+		v, ok := req.Meta[AuthorizationKey]
+		if !ok || !strings.HasPrefix(v, bearerPrefix) {
+			return nil, ErrNoCredentials
+		}
+
+		p, err := verify(ctx, strings.TrimPrefix(v, bearerPrefix))
+		if err != nil {
+			return nil, unauthenticated("micro：token校验失败, " + err.Error())
+		}
+
+		return p, nil
+	})
+}
+
+// APIKey 认证x-api-key元数据中的api key，keys为api key到调用方的映射
+func APIKey(keys map[string]*Principal) Authenticator {
+	// 按照摘要保存，避免比较api key时泄露时间信息
+	digests := make(map[[sha256.Size]byte]*Principal, len(keys))
+	for k, p := range keys {
+		digests[sha256.Sum256([]byte(k))] = p
+	}
+
+	return AuthenticatorFunc(func(ctx context.Context, req *message.Request) (*Principal, error) {
+		v, ok := req.Meta[APIKeyKey]
+		if !ok {
+			return nil, ErrNoCredentials
+		}
+
+		p, ok := digests[sha256.Sum256([]byte(v))]
+		if !ok {
+			return nil, unauthenticated("micro：api key不合法")
+		}
+
+		return p, nil
+	})
+}
+
+type HMACOpt func(h *hmacAuthenticator)
+
+// WithMaxSkew 设置签名时间与服务端时间允许的最大偏差，默认为defaultMaxSkew，超过偏差的签名视为过期
+func WithMaxSkew(d time.Duration) HMACOpt {
+	return func(h *hmacAuthenticator) {
+		h.maxSkew = d
+	}
+}
+
+// WithNonceCache 设置记录已使用nonce的缓存，默认只在当前进程内记录，
+// 多个实例部署时需要使用共享的缓存，否则同一个请求可以被重放到其他实例
+func WithNonceCache(c NonceCache) HMACOpt {
+	return func(h *hmacAuthenticator) {
+		h.nonces = c
+	}
+}
+
+const defaultMaxSkew = 5 * time.Minute
+
+// NonceCache 记录已经使用过的nonce，用于拒绝重放的请求
+type NonceCache interface {
+	// Use 记录nonce，nonce在expire之前已经被使用过时返回false
+	Use(nonce string, expire time.Time) bool
+}
+
+type hmacAuthenticator struct {
+	secret  func(keyID string) ([]byte, *Principal, bool)
+	maxSkew time.Duration
+	nonces  NonceCache
+	now     func() time.Time
+}
+
+// HMAC 认证请求的HMAC-SHA256签名，secret根据key id返回密钥以及对应的调用方
+// 签名的内容见Sign，客户端可以使用HMACCredentials生成签名。
+// 签名时间超过允许的偏差时视为过期，偏差范围内同一个nonce只能使用一次
+func HMAC(secret func(keyID string) ([]byte, *Principal, bool), opts ...HMACOpt) Authenticator {
+	h := &hmacAuthenticator{
+		secret:  secret,
+		maxSkew: defaultMaxSkew,
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.nonces == nil {
+		h.nonces = newMemoryNonceCache(func() time.Time {
+			return h.now()
+		})
+	}
+
+	return h
+}
+
+func (h *hmacAuthenticator) Authenticate(ctx context.Context, req *message.Request) (*Principal, error) {
+	sig, ok := req.Meta[SignatureKey]
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	keyID, ts, nonce := req.Meta[SignatureKeyIDKey], req.Meta[SignatureTimestampKey], req.Meta[SignatureNonceKey]
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, unauthenticated("micro：签名时间格式不对")
+	}
+
+	signedAt := time.Unix(unix, 0)
+	if skew := h.now().Sub(signedAt); skew > h.maxSkew || skew < -h.maxSkew {
+		return nil, unauthenticated("micro：签名已过期")
+	}
+
+	if nonce == "" {
+		return nil, unauthenticated("micro：签名缺少nonce")
+	}
+
+	secret, p, ok := h.secret(keyID)
+	if !ok {
+		return nil, unauthenticated("micro：签名的key不存在")
+	}
+
+	want := Sign(secret, req)
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return nil, unauthenticated("micro：签名不正确")
+	}
+
+	// 签名正确之后才记录nonce，避免伪造的请求占用nonce。超过偏差之后签名已经过期，不需要继续记录
+	if !h.nonces.Use(keyID+"\n"+nonce, signedAt.Add(h.maxSkew)) {
+		return nil, unauthenticated("micro：签名已经被使用")
+	}
+
+	return p, nil
+}
+
+// Sign 计算请求的HMAC-SHA256签名，签名的内容为
+//
+//	服务名\n方法名\n
+//	按照key排序的元数据，每一项为 key\rvalue\n，不包括签名本身
+//	请求数据
+//
+// 元数据包括key id、时间戳、nonce以及分组、版本等路由信息，请求数据为压缩之后实际发送的数据
+func Sign(secret []byte, req *message.Request) string {
+	keys := make([]string, 0, len(req.Meta))
+	for k := range req.Meta {
+		if k != SignatureKey {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(req.ServiceName + "\n" + req.MethodName + "\n"))
+	for _, k := range keys {
+		mac.Write([]byte(k + "\r" + req.Meta[k] + "\n"))
+	}
+	mac.Write(req.Data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// memoryNonceCache 进程内的NonceCache，定期清理已经过期的nonce
+type memoryNonceCache struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	nextSweep time.Time
+}
+
+const nonceSweepInterval = time.Minute
+
+func newMemoryNonceCache(now func() time.Time) *memoryNonceCache {
+	return &memoryNonceCache{
+		now:    now,
+		nonces: make(map[string]time.Time),
+	}
+}
+
+func (c *memoryNonceCache) Use(nonce string, expire time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	if now.After(c.nextSweep) {
+		for n, exp := range c.nonces {
+			if !exp.After(now) {
+				delete(c.nonces, n)
+			}
+		}
+		c.nextSweep = now.Add(nonceSweepInterval)
+	}
+
+	if exp, ok := c.nonces[nonce]; ok && exp.After(now) {
+		return false
+	}
+
+	c.nonces[nonce] = expire
+
+	return true
+}
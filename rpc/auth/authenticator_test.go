@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/status"
+)
+
+func TestChain(t *testing.T) {
+	a := Chain(
+		Bearer(func(ctx context.Context, token string) (*Principal, error) {
+			if token == "token" {
+				return &Principal{Name: "alice"}, nil
+			}
+			return nil, errors.New("token不存在")
+		}),
+		APIKey(map[string]*Principal{"key": {Name: "bob"}}),
+	)
+
+	testCases := []struct {
+		name     string
+		meta     map[string]string
+		want     *Principal
+		wantCode status.Code
+	}{
+		{
+			name: "bearer",
+			meta: map[string]string{AuthorizationKey: "Bearer token"},
+			want: &Principal{Name: "alice"},
+		},
+		{
+			name: "api key",
+			meta: map[string]string{APIKeyKey: "key"},
+			want: &Principal{Name: "bob"},
+		},
+		{
+			name:     "invalid token",
+			meta:     map[string]string{AuthorizationKey: "Bearer unknown"},
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "invalid api key",
+			meta:     map[string]string{APIKeyKey: "unknown"},
+			wantCode: status.Unauthenticated,
+		},
+		{
+			// 不是bearer token，由下一个Authenticator处理
+			name:     "basic auth",
+			meta:     map[string]string{AuthorizationKey: "Basic YWxpY2U6cGFzcw=="},
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "no credentials",
+			wantCode: status.Unauthenticated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &message.Request{RequestHeader: message.RequestHeader{Meta: tc.meta}}
+			p, err := a.Authenticate(context.Background(), req)
+			assert.Equal(t, tc.wantCode, status.CodeOf(err))
+			assert.Equal(t, tc.want, p)
+		})
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	secret := []byte("secret")
+
+	a := newTestHMAC(secret, now)
+
+	sign := func(keyID string, secret []byte, ts time.Time, nonce string, data []byte) map[string]string {
+		req := &message.Request{
+			RequestHeader: message.RequestHeader{
+				ServiceName: "user-service",
+				MethodName:  "GetById",
+				Meta: map[string]string{
+					"sys_version":         "1.0.0",
+					SignatureKeyIDKey:     keyID,
+					SignatureTimestampKey: strconv.FormatInt(ts.Unix(), 10),
+					SignatureNonceKey:     nonce,
+				},
+			},
+			Data: data,
+		}
+		req.Meta[SignatureKey] = Sign(secret, req)
+		return req.Meta
+	}
+
+	with := func(meta map[string]string, k, v string) map[string]string {
+		meta[k] = v
+		return meta
+	}
+
+	testCases := []struct {
+		name     string
+		meta     map[string]string
+		wantCode status.Code
+	}{
+		{
+			name: "valid",
+			meta: sign("order-service", secret, now, "n1", []byte("data")),
+		},
+		{
+			name: "within skew",
+			meta: sign("order-service", secret, now.Add(-30*time.Second), "n2", []byte("data")),
+		},
+		{
+			name:     "expired",
+			meta:     sign("order-service", secret, now.Add(-2*time.Minute), "n3", []byte("data")),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "future",
+			meta:     sign("order-service", secret, now.Add(2*time.Minute), "n4", []byte("data")),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "unknown key",
+			meta:     sign("pay-service", secret, now, "n5", []byte("data")),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "wrong secret",
+			meta:     sign("order-service", []byte("wrong"), now, "n6", []byte("data")),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "tampered data",
+			meta:     sign("order-service", secret, now, "n7", []byte("other")),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			// 版本等路由信息也包含在签名中
+			name:     "tampered meta",
+			meta:     with(sign("order-service", secret, now, "n8", []byte("data")), "sys_version", "2.0.0"),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "added meta",
+			meta:     with(sign("order-service", secret, now, "n9", []byte("data")), "sys_group", "canary"),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			// 使用同一个密钥的另一个key id
+			name:     "tampered key id",
+			meta:     with(sign("order-service", secret, now, "n10", []byte("data")), SignatureKeyIDKey, "order-service-v2"),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "no nonce",
+			meta:     sign("order-service", secret, now, "", []byte("data")),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "invalid timestamp",
+			meta:     map[string]string{SignatureKey: "abc", SignatureTimestampKey: "now"},
+			wantCode: status.Unauthenticated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &message.Request{
+				RequestHeader: message.RequestHeader{
+					ServiceName: "user-service",
+					MethodName:  "GetById",
+					Meta:        tc.meta,
+				},
+				Data: []byte("data"),
+			}
+			_, err := a.Authenticate(context.Background(), req)
+			assert.Equal(t, tc.wantCode, status.CodeOf(err))
+		})
+	}
+}
+
+func TestHMAC_Replay(t *testing.T) {
+	secret := []byte("secret")
+
+	// HMACCredentials使用当前时间签名
+	a := newTestHMAC(secret, time.Now())
+	a.now = time.Now
+
+	req := &message.Request{
+		RequestHeader: message.RequestHeader{
+			ServiceName: "user-service",
+			MethodName:  "GetById",
+		},
+		Data: []byte("data"),
+	}
+	md, err := HMACCredentials("order-service", secret).Metadata(context.Background(), req)
+	assert.NoError(t, err)
+	req.Meta = md
+
+	_, err = a.Authenticate(context.Background(), req)
+	assert.NoError(t, err)
+
+	// 偏差范围内重放同一个请求
+	_, err = a.Authenticate(context.Background(), req)
+	assert.Equal(t, status.Unauthenticated, status.CodeOf(err))
+
+	// 每次生成的nonce都不相同
+	md, err = HMACCredentials("order-service", secret).Metadata(context.Background(), req)
+	assert.NoError(t, err)
+	assert.NotEqual(t, req.Meta[SignatureNonceKey], md[SignatureNonceKey])
+}
+
+func TestMemoryNonceCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	c := newMemoryNonceCache(func() time.Time {
+		return now
+	})
+
+	assert.True(t, c.Use("a", now.Add(time.Minute)))
+	assert.False(t, c.Use("a", now.Add(time.Minute)))
+	assert.True(t, c.Use("b", now.Add(time.Minute)))
+
+	// 过期之后清理，可以再次使用
+	now = now.Add(2 * time.Minute)
+	assert.True(t, c.Use("a", now.Add(time.Minute)))
+	assert.Len(t, c.nonces, 1)
+}
+
+func newTestHMAC(secret []byte, now time.Time) *hmacAuthenticator {
+	a := HMAC(func(keyID string) ([]byte, *Principal, bool) {
+		if keyID != "order-service" && keyID != "order-service-v2" {
+			return nil, nil, false
+		}
+		return secret, &Principal{Name: keyID}, true
+	}, WithMaxSkew(time.Minute)).(*hmacAuthenticator)
+	a.now = func() time.Time {
+		return now
+	}
+	return a
+}
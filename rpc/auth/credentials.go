@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/uzziahlin/transport/rpc/message"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Credentials 客户端凭证，每次调用前返回需要附加到请求中的元数据
+// req为即将发送的请求，Data为压缩之后的数据
+type Credentials interface {
+	Metadata(ctx context.Context, req *message.Request) (map[string]string, error)
+}
+
+// Token 带有过期时间的token，Expiry为零值代表永不过期
+type Token struct {
+	Value  string
+	Expiry time.Time
+}
+
+// TokenSource 获取token，例如从认证服务申请
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc 函数形式的TokenSource
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// StaticToken 永不过期的token
+func StaticToken(token string) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		return &Token{Value: token}, nil
+	})
+}
+
+// ReuseTokenSource 缓存src返回的token，在token过期前refreshBefore时重新获取
+// 多个并发调用同时发现token需要刷新时，只会有一个调用请求src
+func ReuseTokenSource(src TokenSource, refreshBefore time.Duration) TokenSource {
+	return &reuseTokenSource{
+		src:           src,
+		refreshBefore: refreshBefore,
+		now:           time.Now,
+	}
+}
+
+type reuseTokenSource struct {
+	src           TokenSource
+	refreshBefore time.Duration
+	now           func() time.Time
+
+	mu    sync.Mutex
+	token *Token
+}
+
+func (r *reuseTokenSource) Token(ctx context.Context) (*Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.token != nil && (r.token.Expiry.IsZero() || r.now().Add(r.refreshBefore).Before(r.token.Expiry)) {
+		return r.token, nil
+	}
+
+	token, err := r.src.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.token = token
+
+	return token, nil
+}
+
+// BearerCredentials 将src提供的token以Authorization: Bearer的形式附加到请求中
+func BearerCredentials(src TokenSource) Credentials {
+	return credentialsFunc(func(ctx context.Context, req *message.Request) (map[string]string, error) {
+		token, err := src.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{AuthorizationKey: bearerPrefix + token.Value}, nil
+	})
+}
+
+// APIKeyCredentials 将api key附加到请求中
+func APIKeyCredentials(key string) Credentials {
+	return credentialsFunc(func(ctx context.Context, req *message.Request) (map[string]string, error) {
+		return map[string]string{APIKeyKey: key}, nil
+	})
+}
+
+// HMACCredentials 使用secret对请求签名，keyID用于服务端查找密钥
+// 每次调用都会生成新的nonce，签名覆盖请求中已有的元数据以及本次附加的元数据
+func HMACCredentials(keyID string, secret []byte) Credentials {
+	return credentialsFunc(func(ctx context.Context, req *message.Request) (map[string]string, error) {
+		nonce, err := newNonce()
+		if err != nil {
+			return nil, err
+		}
+
+		md := map[string]string{
+			SignatureKeyIDKey:     keyID,
+			SignatureTimestampKey: strconv.FormatInt(time.Now().Unix(), 10),
+			SignatureNonceKey:     nonce,
+		}
+
+		signed := *req
+		signed.Meta = make(map[string]string, len(req.Meta)+len(md))
+		for k, v := range req.Meta {
+			signed.Meta[k] = v
+		}
+		for k, v := range md {
+			signed.Meta[k] = v
+		}
+
+		md[SignatureKey] = Sign(secret, &signed)
+
+		return md, nil
+	})
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type credentialsFunc func(ctx context.Context, req *message.Request) (map[string]string, error)
+
+func (f credentialsFunc) Metadata(ctx context.Context, req *message.Request) (map[string]string, error) {
+	return f(ctx, req)
+}
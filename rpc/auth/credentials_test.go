@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReuseTokenSource(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	cnt := 0
+	src := ReuseTokenSource(TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		cnt++
+		return &Token{
+			Value:  strconv.Itoa(cnt),
+			Expiry: now.Add(time.Minute),
+		}, nil
+	}), 10*time.Second).(*reuseTokenSource)
+	src.now = func() time.Time {
+		return now
+	}
+
+	testCases := []struct {
+		name  string
+		after time.Duration
+		want  string
+	}{
+		{
+			name: "first",
+			want: "1",
+		},
+		{
+			name:  "cached",
+			after: 49 * time.Second,
+			want:  "1",
+		},
+		{
+			// 距离过期不足10秒，提前刷新
+			name:  "refresh before expiry",
+			after: 50 * time.Second,
+			want:  "2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src.now = func() time.Time {
+				return now.Add(tc.after)
+			}
+			token, err := src.Token(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, token.Value)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/uzziahlin/transport/rpc/status"
+)
+
+// Any 匹配任意的服务名或者方法名
+const Any = "*"
+
+// Rule 授权规则，调用方的名字在Principals中，或者拥有Roles中的任意一个角色时，允许调用匹配的方法
+// Principals和Roles都为空时，允许任意认证通过的调用方调用匹配的方法
+type Rule struct {
+	Service    string
+	Method     string
+	Principals []string
+	Roles      []string
+}
+
+func (r Rule) matches(service, method string) bool {
+	return (r.Service == Any || r.Service == service) && (r.Method == Any || r.Method == method)
+}
+
+func (r Rule) allows(p *Principal) bool {
+	if len(r.Principals) == 0 && len(r.Roles) == 0 {
+		return true
+	}
+
+	for _, name := range r.Principals {
+		if name == p.Name {
+			return true
+		}
+	}
+
+	for _, role := range r.Roles {
+		if p.HasRole(role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Policy 声明式的授权策略，默认拒绝，只要有一条规则允许就可以调用
+type Policy struct {
+	Rules []Rule
+}
+
+// NewPolicy 根据规则创建授权策略
+func NewPolicy(rules ...Rule) *Policy {
+	return &Policy{
+		Rules: rules,
+	}
+}
+
+// Authorize 判断调用方能否调用service的method，不能调用时返回*PermissionDeniedError
+func (p *Policy) Authorize(principal *Principal, service, method string) error {
+	if principal != nil {
+		for _, r := range p.Rules {
+			if r.matches(service, method) && r.allows(principal) {
+				return nil
+			}
+		}
+	}
+
+	return &PermissionDeniedError{
+		Principal: principal,
+		Service:   service,
+		Method:    method,
+	}
+}
+
+// PermissionDeniedError 调用方没有权限调用方法，传递给客户端时转换为status.PermissionDenied
+type PermissionDeniedError struct {
+	Principal *Principal
+	Service   string
+	Method    string
+}
+
+func (e *PermissionDeniedError) Error() string {
+	name := "anonymous"
+	if e.Principal != nil {
+		name = e.Principal.Name
+	}
+	return fmt.Sprintf("micro：%s 没有权限调用 %s.%s", name, e.Service, e.Method)
+}
+
+func (e *PermissionDeniedError) Status() *status.Status {
+	return status.New(status.PermissionDenied, e.Error())
+}
+
+// IsPermissionDenied 判断错误是否为没有权限，客户端收到的错误为status.PermissionDenied
+func IsPermissionDenied(err error) bool {
+	return status.CodeOf(err) == status.PermissionDenied
+}
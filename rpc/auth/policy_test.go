@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uzziahlin/transport/rpc/status"
+)
+
+func TestPolicy_Authorize(t *testing.T) {
+	policy := NewPolicy(
+		Rule{Service: "user-service", Method: "GetById"},
+		Rule{Service: "user-service", Method: Any, Roles: []string{"admin"}},
+		Rule{Service: Any, Method: "Ping", Principals: []string{"monitor"}},
+	)
+
+	testCases := []struct {
+		name      string
+		principal *Principal
+		service   string
+		method    string
+		wantErr   error
+	}{
+		{
+			name:      "any authenticated principal",
+			principal: &Principal{Name: "bob"},
+			service:   "user-service",
+			method:    "GetById",
+		},
+		{
+			name:      "role",
+			principal: &Principal{Name: "alice", Roles: []string{"admin"}},
+			service:   "user-service",
+			method:    "Delete",
+		},
+		{
+			name:      "principal",
+			principal: &Principal{Name: "monitor"},
+			service:   "order-service",
+			method:    "Ping",
+		},
+		{
+			name:      "missing role",
+			principal: &Principal{Name: "bob", Roles: []string{"guest"}},
+			service:   "user-service",
+			method:    "Delete",
+			wantErr: &PermissionDeniedError{
+				Principal: &Principal{Name: "bob", Roles: []string{"guest"}},
+				Service:   "user-service",
+				Method:    "Delete",
+			},
+		},
+		{
+			name:    "anonymous",
+			service: "user-service",
+			method:  "GetById",
+			wantErr: &PermissionDeniedError{Service: "user-service", Method: "GetById"},
+		},
+		{
+			name:      "no matching rule",
+			principal: &Principal{Name: "alice", Roles: []string{"admin"}},
+			service:   "order-service",
+			method:    "Create",
+			wantErr: &PermissionDeniedError{
+				Principal: &Principal{Name: "alice", Roles: []string{"admin"}},
+				Service:   "order-service",
+				Method:    "Create",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Authorize(tc.principal, tc.service, tc.method)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				assert.True(t, IsPermissionDenied(err))
+				assert.Equal(t, status.PermissionDenied, status.CodeOf(err))
+			}
+		})
+	}
+}
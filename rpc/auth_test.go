@@ -0,0 +1,135 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"github.com/uzziahlin/transport/rpc"
+	"github.com/uzziahlin/transport/rpc/auth"
+	"github.com/uzziahlin/transport/rpc/rpctest"
+	"github.com/uzziahlin/transport/rpc/status"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+
+	authenticator := auth.Chain(
+		auth.Bearer(func(ctx context.Context, token string) (*auth.Principal, error) {
+			switch token {
+			case "admin-token":
+				return &auth.Principal{Name: "alice", Roles: []string{"admin"}}, nil
+			case "guest-token":
+				return &auth.Principal{Name: "bob", Roles: []string{"guest"}}, nil
+			}
+			return nil, errors.New("token不存在")
+		}),
+		auth.APIKey(map[string]*auth.Principal{
+			"key-1": {Name: "batch-job"},
+		}),
+		auth.HMAC(func(keyID string) ([]byte, *auth.Principal, bool) {
+			if keyID != "order-service" {
+				return nil, nil, false
+			}
+			return []byte("secret"), &auth.Principal{Name: "order-service"}, true
+		}),
+	)
+
+	policy := auth.NewPolicy(
+		auth.Rule{Service: "user-service", Method: "GetById", Roles: []string{"admin"}},
+		auth.Rule{Service: auth.Any, Method: auth.Any, Principals: []string{"batch-job", "order-service"}},
+	)
+
+	testCases := []struct {
+		name     string
+		creds    auth.Credentials
+		want     string
+		wantCode status.Code
+	}{
+		{
+			name:  "bearer",
+			creds: auth.BearerCredentials(auth.StaticToken("admin-token")),
+			want:  "alice",
+		},
+		{
+			name:  "api key",
+			creds: auth.APIKeyCredentials("key-1"),
+			want:  "batch-job",
+		},
+		{
+			name:  "hmac",
+			creds: auth.HMACCredentials("order-service", []byte("secret")),
+			want:  "order-service",
+		},
+		{
+			name:     "no credentials",
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "invalid token",
+			creds:    auth.BearerCredentials(auth.StaticToken("unknown")),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "invalid signature",
+			creds:    auth.HMACCredentials("order-service", []byte("wrong")),
+			wantCode: status.Unauthenticated,
+		},
+		{
+			name:     "permission denied",
+			creds:    auth.BearerCredentials(auth.StaticToken("guest-token")),
+			wantCode: status.PermissionDenied,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			endpoint := rpc.NewEndPoint("", rpc.WithAuthenticator(authenticator), rpc.WithAuthorization(policy))
+			endpoint.Register(&principalService{})
+
+			server := rpctest.NewInMemoryServer(t, endpoint)
+
+			var opts []rpc.ConstructorOpt
+			if tc.creds != nil {
+				opts = append(opts, rpc.WithCredentials(tc.creds))
+			}
+
+			userService := &rpc.UserService{}
+			require.NoError(t, server.NewConstructor(opts...).InitProxy(userService))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			resp, err := userService.GetById(ctx, &rpc.UserReq{Id: "1"})
+			if tc.wantCode != status.OK {
+				assert.Equal(t, tc.wantCode, status.CodeOf(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, resp.Content)
+		})
+	}
+}
+
+// principalService 返回认证通过的调用方的名字
+type principalService struct{}
+
+func (s *principalService) GetById(ctx context.Context, req *rpc.UserReq) (*rpc.UserResp, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return &rpc.UserResp{Content: "anonymous"}, nil
+	}
+	return &rpc.UserResp{Content: p.Name}, nil
+}
+
+func (s *principalService) Info() rpc.ServiceInfo {
+	return rpc.ServiceInfo{
+		ServiceName: "user-service",
+	}
+}
@@ -0,0 +1,101 @@
+package rpc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/serialize/json"
+)
+
+func newBenchmarkRequest(b *testing.B) *message.Request {
+	data, err := (&json.Serializer{}).Serialize(&UserReq{Id: "1"})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return &message.Request{
+		RequestHeader: message.RequestHeader{
+			Header: message.Header{
+				MessageId:  1,
+				Serializer: (&json.Serializer{}).Code(),
+			},
+			ServiceName: "user-service",
+			MethodName:  "GetById",
+			Meta:        map[string]string{},
+		},
+		Data: data,
+	}
+}
+
+// BenchmarkReflectionStub_Invoke 查找方法、反序列化请求、调用服务方法以及序列化响应
+func BenchmarkReflectionStub_Invoke(b *testing.B) {
+	endpoint := NewEndPoint("")
+	endpoint.Register(&UserServiceImpl{})
+
+	stub, err := endpoint.service("user-service", "", "")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	req := newBenchmarkRequest(b)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err = stub.invoke(ctx, req, newCallStats()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkEndPoint_Invoke 服务端处理一个请求的完整流程，不包含网络读写
+func BenchmarkEndPoint_Invoke(b *testing.B) {
+	endpoint := NewEndPoint("")
+	endpoint.Register(&UserServiceImpl{})
+
+	req := newBenchmarkRequest(b)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		resp, err := endpoint.Invoke(ctx, req)
+		if err != nil || resp.Error != "" {
+			b.Fatal(err, resp.Error)
+		}
+	}
+}
+
+// BenchmarkMethodLookup 对比每次请求通过反射查找方法与使用注册时解析的方法表
+func BenchmarkMethodLookup(b *testing.B) {
+	val := reflect.ValueOf(&UserServiceImpl{})
+
+	b.Run("MethodByName", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			method := val.MethodByName("GetById")
+			if !method.IsValid() || validateFunc(method.Type()) != "" {
+				b.Fatal("方法不存在")
+			}
+			_ = reflect.New(method.Type().In(1).Elem())
+		}
+	})
+
+	b.Run("methodTable", func(b *testing.B) {
+		methods, _, _ := newMethodTable(val)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			method, ok := methods["GetById"]
+			if !ok {
+				b.Fatal("方法不存在")
+			}
+			_ = reflect.New(method.argElem)
+		}
+	})
+}
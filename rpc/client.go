@@ -2,9 +2,13 @@ package rpc
 
 import (
 	"context"
-	"github.com/uzziahlin/transport/rpc/errs"
-	"log"
+	"errors"
+	"github.com/uzziahlin/transport/rpc/message"
+	"io"
 	"net"
+	"os"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -12,19 +16,26 @@ type Client interface {
 	Send(ctx context.Context, data []byte) ([]byte, error)
 }
 
+// Dialer 定义了客户端如何与服务端建立连接，默认使用NetTransport，支持tcp以及unix domain socket
+type Dialer func(ctx context.Context, addr string) (net.Conn, error)
+
+// NewRpcClient addr的格式见NetTransport，例如localhost:8080或者unix:///run/rpc.sock
 func NewRpcClient(addr string) *DefaultClient {
-	pool := &ConnPool[net.Conn]{
-		idleConns:   make(chan *Conn[net.Conn], 10),
+	return newRpcClient(addr, defaultTransport.Dial)
+}
+
+func newRpcClient(addr string, dial Dialer) *DefaultClient {
+	pool := &ConnPool[*clientConn]{
+		idleConns:   make(chan *Conn[*clientConn], 10),
 		maxActive:   20,
 		maxIdleTime: 15 * time.Second,
-		waitQ:       make(map[uint64]chan *Conn[net.Conn], 16),
-		factory: func() net.Conn {
-			conn, err := net.Dial("tcp", addr)
+		waitQ:       make(map[uint64]chan *Conn[*clientConn], 16),
+		factory: func(ctx context.Context) (*clientConn, error) {
+			conn, err := dial(ctx, addr)
 			if err != nil {
-				log.Fatalf("连接创建失败")
-				return nil
+				return nil, err
 			}
-			return conn
+			return newClientConn(conn), nil
 		},
 	}
 
@@ -38,25 +49,178 @@ func NewRpcClient(addr string) *DefaultClient {
 
 type DefaultClient struct {
 	read Reader
-	pool Pool[net.Conn]
+	pool Pool[*clientConn]
 }
 
-func (r DefaultClient) Send(ctx context.Context, data []byte) ([]byte, error) {
+// errConnDrained 连接在收到GOAWAY帧之后被服务端关闭，并且没有收到响应，说明请求没有被服务端处理
+var errConnDrained = errors.New("micro: 连接已被服务端关闭")
+
+// Send 发送请求并读取响应，oneway请求写入成功之后直接返回nil
+// 服务端优雅关闭时没有处理的请求会在新的连接上重试一次
+func (r *DefaultClient) Send(ctx context.Context, data []byte) ([]byte, error) {
+	res, err := r.send(ctx, data)
+
+	if errors.Is(err, errConnDrained) {
+		res, err = r.send(ctx, data)
+	}
+
+	return res, err
+}
+
+// send ctx的过期时间会被设置为连接的读写过期时间，ctx被取消时会立即打断阻塞中的读写，
+// 被打断、读写出错或者收到GOAWAY帧的连接不会再放回连接池
+func (r *DefaultClient) send(ctx context.Context, data []byte) ([]byte, error) {
 	conn, err := r.pool.Get(ctx)
-	defer r.pool.Put(ctx, conn)
 
 	if err != nil {
 		return nil, err
 	}
+
+	stop := conn.watch(ctx)
+
+	res, err := r.roundTrip(ctx, conn, data)
+
+	if interrupted := stop(); interrupted || err != nil {
+		_ = r.pool.Remove(ctx, conn)
+		return nil, ctxErr(ctx, err)
+	}
+
+	if conn.draining {
+		_ = r.pool.Remove(ctx, conn)
+	} else {
+		_ = r.pool.Put(ctx, conn)
+	}
+
+	return res, nil
+}
+
+// Close 关闭连接池，空闲连接会被立即关闭，使用中的连接在归还时关闭
+func (r *DefaultClient) Close() error {
+	return r.pool.Close()
+}
+
+func (r *DefaultClient) roundTrip(ctx context.Context, conn *clientConn, data []byte) ([]byte, error) {
+	deadline, _ := ctx.Deadline()
+
+	// 没有过期时间时deadline为零值，代表清除上一次调用设置的过期时间
+	err := conn.SetDeadline(deadline)
+
+	if err != nil {
+		return nil, err
+	}
+
 	_, err = conn.Write(data)
 
 	if err != nil {
 		return nil, err
 	}
 
+	// oneway请求服务端不会写回响应，写入成功即代表调用成功
 	if isOneway(ctx) {
-		return nil, errs.ErrOneway
+		return nil, nil
 	}
 
-	return r.read(conn)
+	for {
+		res, err := r.read(conn)
+
+		if err != nil {
+			// 服务端只会在没有处理中的请求时关闭drain状态的连接，所以此时请求一定没有被处理
+			if conn.draining && (errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET)) {
+				return nil, errConnDrained
+			}
+			return nil, err
+		}
+
+		// GOAWAY帧之后仍然可能收到当前请求的响应
+		if message.IsGoAway(res) {
+			conn.draining = true
+			continue
+		}
+
+		return res, nil
+	}
+}
+
+// ctxErr 如果读写是因为ctx取消或者过期被打断的，返回ctx对应的错误
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// 连接的过期时间和ctx的过期时间相同，连接可能先于ctx感知到过期
+	if _, ok := ctx.Deadline(); ok && errors.Is(err, os.ErrDeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+
+	return err
+}
+
+// aLongTimeAgo 一个过去的时间，设置为连接的过期时间可以立即打断阻塞中的读写
+var aLongTimeAgo = time.Unix(1, 0)
+
+// clientConn 客户端连接
+// 每个连接持有一个常驻的g监听当前调用的ctx，而不是每次调用都启动一个新的g
+type clientConn struct {
+	net.Conn
+	watchC      chan context.Context
+	stopC       chan struct{}
+	closeC      chan struct{}
+	closeOnce   sync.Once
+	interrupted bool // 通过watchC以及stopC与loop同步，不需要额外加锁
+	draining    bool // 收到了服务端的GOAWAY帧，只会被持有连接的调用读写
+}
+
+func newClientConn(conn net.Conn) *clientConn {
+	c := &clientConn{
+		Conn:   conn,
+		watchC: make(chan context.Context),
+		stopC:  make(chan struct{}),
+		closeC: make(chan struct{}),
+	}
+
+	go c.loop()
+
+	return c
+}
+
+func (c *clientConn) loop() {
+	for {
+		select {
+		case ctx := <-c.watchC:
+			select {
+			case <-ctx.Done():
+				c.interrupted = true
+				_ = c.Conn.SetDeadline(aLongTimeAgo)
+				<-c.stopC
+			case <-c.stopC:
+			}
+		case <-c.closeC:
+			return
+		}
+	}
+}
+
+// watch 开始监听ctx，返回的函数用于结束监听，并报告本次调用是否被ctx打断
+// 同一时刻一个连接只会被一个调用持有，所以watch和stop总是成对且串行调用
+func (c *clientConn) watch(ctx context.Context) (stop func() bool) {
+	if ctx.Done() == nil {
+		return func() bool {
+			return false
+		}
+	}
+
+	c.interrupted = false
+	c.watchC <- ctx
+
+	return func() bool {
+		c.stopC <- struct{}{}
+		return c.interrupted
+	}
+}
+
+func (c *clientConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closeC)
+	})
+	return c.Conn.Close()
 }
@@ -0,0 +1,209 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/message"
+)
+
+func TestDefaultClient_Send(t *testing.T) {
+	// 服务端收到请求之后原样返回
+	addr := startFakeServer(t, func(conn net.Conn) {
+		for {
+			data, err := RpcReader(conn)
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write(data)
+		}
+	})
+
+	client := NewRpcClient(addr)
+
+	// 消息id为0的响应是GOAWAY帧，请求id从1开始
+	data := (&message.DefaultRequestEncoder{}).Encode(&message.Request{
+		RequestHeader: message.RequestHeader{
+			Header:      message.Header{MessageId: 1},
+			ServiceName: "user-service",
+			MethodName:  "GetById",
+		},
+		Data: []byte("hello"),
+	})
+
+	for i := 0; i < 3; i++ {
+		res, err := client.Send(context.Background(), data)
+		require.NoError(t, err)
+		assert.Equal(t, data, res)
+	}
+
+	// 连接应该被复用
+	assert.Equal(t, 1, activeConns(client))
+}
+
+func TestDefaultClient_SendInterrupted(t *testing.T) {
+	// 服务端只读取请求，永远不返回响应
+	addr := startFakeServer(t, func(conn net.Conn) {
+		for {
+			if _, err := RpcReader(conn); err != nil {
+				return
+			}
+		}
+	})
+
+	data := (&message.DefaultRequestEncoder{}).Encode(&message.Request{})
+
+	testCases := []struct {
+		name    string
+		ctx     func() (context.Context, context.CancelFunc)
+		wantErr error
+	}{
+		{
+			name: "deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 100*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "cancel",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(100*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewRpcClient(addr)
+
+			ctx, cancel := tc.ctx()
+			defer cancel()
+
+			start := time.Now()
+			_, err := client.Send(ctx, data)
+
+			assert.Equal(t, tc.wantErr, err)
+			assert.Less(t, time.Since(start), time.Second)
+
+			// 被打断的连接不能再放回连接池
+			assert.Equal(t, 0, activeConns(client))
+		})
+	}
+}
+
+func TestDefaultClient_SendDialErr(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	client := NewRpcClient(addr)
+
+	_, err = client.Send(context.Background(), []byte("hello"))
+	assert.Error(t, err)
+	assert.Equal(t, 0, activeConns(client))
+}
+
+func TestDefaultClient_SendGoAway(t *testing.T) {
+	goAway := (&message.DefaultResponseEncoder{}).Encode(&message.Response{})
+
+	testCases := []struct {
+		name string
+		// first 第一个连接的处理方式
+		first     func(conn net.Conn)
+		wantConns int32
+	}{
+		{
+			// 服务端在处理请求之前进入drain状态并关闭连接，请求会在新的连接上重试
+			name: "retry on new conn",
+			first: func(conn net.Conn) {
+				_, _ = RpcReader(conn)
+				_, _ = conn.Write(goAway)
+			},
+			wantConns: 2,
+		},
+		{
+			// 服务端处理完请求之后关闭连接
+			name: "response after goaway",
+			first: func(conn net.Conn) {
+				data, _ := RpcReader(conn)
+				_, _ = conn.Write(goAway)
+				_, _ = conn.Write(data)
+			},
+			wantConns: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var conns int32
+
+			addr := startFakeServer(t, func(conn net.Conn) {
+				if atomic.AddInt32(&conns, 1) == 1 {
+					tc.first(conn)
+					return
+				}
+				data, err := RpcReader(conn)
+				if err == nil {
+					_, _ = conn.Write(data)
+				}
+			})
+
+			client := NewRpcClient(addr)
+
+			data := (&message.DefaultRequestEncoder{}).Encode(&message.Request{
+				RequestHeader: message.RequestHeader{
+					Header: message.Header{MessageId: 1},
+				},
+			})
+
+			res, err := client.Send(context.Background(), data)
+			require.NoError(t, err)
+			assert.Equal(t, data, res)
+			assert.Equal(t, tc.wantConns, atomic.LoadInt32(&conns))
+
+			// 收到GOAWAY帧的连接不会放回连接池
+			assert.Equal(t, int(tc.wantConns)-1, activeConns(client))
+		})
+	}
+}
+
+func startFakeServer(t *testing.T, handler ConnHandler) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handler(conn)
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func activeConns(client *DefaultClient) int {
+	pool := client.pool.(*ConnPool[*clientConn])
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return pool.activeCnt
+}
@@ -2,37 +2,99 @@ package rpc
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"github.com/uzziahlin/transport/logger"
+	"github.com/uzziahlin/transport/rpc/auth"
 	"github.com/uzziahlin/transport/rpc/compress"
 	"github.com/uzziahlin/transport/rpc/compress/gzip"
 	"github.com/uzziahlin/transport/rpc/compress/zip"
 	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/metadata"
+	"github.com/uzziahlin/transport/rpc/metrics"
+	"github.com/uzziahlin/transport/rpc/semver"
 	"github.com/uzziahlin/transport/rpc/serialize"
 	"github.com/uzziahlin/transport/rpc/serialize/json"
+	"github.com/uzziahlin/transport/rpc/serialize/proto"
+	"github.com/uzziahlin/transport/rpc/trace"
 
 	"reflect"
 	"strconv"
+	"sync"
+	"time"
 )
 
 type ConstructorOpt func(constructor *ProxyConstructor)
 
+// WithSerializer 设置默认的序列化协议，没有通过struct tag指定序列化协议的方法都使用该协议
 func WithSerializer(serializer serialize.Serializer) ConstructorOpt {
 	return func(c *ProxyConstructor) {
 		c.serializer = serializer
+		c.RegisterSerializer(serializer)
+	}
+}
+
+// WithTracer 设置tracer，每次调用都会创建一个客户端span
+func WithTracer(tracer *trace.Tracer) ConstructorOpt {
+	return func(c *ProxyConstructor) {
+		c.tracer = tracer
+	}
+}
+
+// WithMetrics 开启客户端的调用指标统计以及连接池指标，指标名以rpc_client_为前缀
+func WithMetrics(reg *metrics.Registry) ConstructorOpt {
+	return func(c *ProxyConstructor) {
+		c.registry = reg
+		c.metrics = newRpcMetrics(reg, "client")
+	}
+}
+
+// WithLogger 设置客户端的logger，默认为logger.Default()
+// 调用失败的错误会返回给调用方，所以客户端只输出Debug级别的日志
+func WithLogger(l logger.Logger) ConstructorOpt {
+	return func(c *ProxyConstructor) {
+		c.logger = l
+	}
+}
+
+// WithDialer 设置建立连接的方式，默认通过tcp拨号，
+// 测试时可以配合rpctest包使用内存连接
+func WithDialer(dial Dialer) ConstructorOpt {
+	return func(c *ProxyConstructor) {
+		c.dial = dial
 	}
 }
 
 type ProxyConstructor struct {
+	mu sync.Mutex
+	// proxies 按照地址共享的远端代理，同一个地址的调用共用一个连接池
+	proxies     map[string]*RemoteProxy
+	dial        Dialer
+	tlsConfig   *tls.Config
+	credentials auth.Credentials
+	logger      logger.Logger
+	msgID       uint32
+	tracer      *trace.Tracer
+	registry    *metrics.Registry
+	metrics     *rpcMetrics
 	serializer  serialize.Serializer
+	serializers map[uint8]serialize.Serializer
 	compressors map[compress.Type]compress.Compressor
 }
 
 func NewProxyConstructor(opts ...ConstructorOpt) *ProxyConstructor {
 	res := &ProxyConstructor{
+		dial:        defaultTransport.Dial,
+		logger:      logger.Default(),
 		serializer:  &json.Serializer{},
+		serializers: make(map[uint8]serialize.Serializer, 4),
 		compressors: make(map[compress.Type]compress.Compressor, 4),
 	}
 
+	res.RegisterSerializer(&json.Serializer{})
+	res.RegisterSerializer(&proto.Serializer{})
+
 	res.RegisterCompressor(&gzip.Compressor{})
 	res.RegisterCompressor(&zip.Compressor{})
 
@@ -40,35 +102,133 @@ func NewProxyConstructor(opts ...ConstructorOpt) *ProxyConstructor {
 		opt(res)
 	}
 
+	// 在WithDialer指定的连接之上使用TLS，与选项的顺序无关
+	if res.tlsConfig != nil {
+		res.dial = tlsDialer(res.dial, res.tlsConfig)
+	}
+
 	return res
 }
 
-func (p ProxyConstructor) RegisterCompressor(compressor compress.Compressor) {
+// RegisterSerializer 注册序列化协议，注册之后可以通过struct tag为方法指定序列化协议
+func (p *ProxyConstructor) RegisterSerializer(serializer serialize.Serializer) {
+	p.serializers[serializer.Code()] = serializer
+}
+
+func (p *ProxyConstructor) RegisterCompressor(compressor compress.Compressor) {
 	p.compressors[compress.Type(compressor.Code())] = compressor
 }
 
 // InitProxy 初始化代理 篡改类型属性 要求类型一定是struct，且为一级指针
-// 要求service属性为函数类型
-// 函数只有两个参数，且第一个参数是context.Context, 第二个参数为真正入参
-// 函数有一个返回值
-func (p ProxyConstructor) InitProxy(service Service) error {
+// 要求service可导出的函数属性形如 func(context.Context, *Req) (*Resp, error)
+// 函数属性可以通过rpc struct tag指定远端方法名以及调用选项，详见parseMethodTag
+// 所有函数属性以及tag会在篡改之前统一校验，不合法时返回*ValidationError，列出每一个不合法的属性
+func (p *ProxyConstructor) InitProxy(service Service) error {
+
+	methods, err := p.resolveMethods(service)
+	if err != nil {
+		return err
+	}
 
-	proxy := NewRemoteProxy(service.Info().Addr)
+	proxy := p.newRemoteProxy(service.Info().Addr)
 
-	return p.setFuncField(service, proxy)
+	p.bindFuncFields(service, proxy, methods)
+
+	return nil
 }
 
-func (p ProxyConstructor) setFuncField(service Service, proxy Proxy) error {
-	if service == nil {
-		return errors.New("micro：入参不能为nil")
+// MustInitProxy 与InitProxy相同，但是初始化失败时会panic，适合在包初始化阶段使用
+func (p *ProxyConstructor) MustInitProxy(service Service) {
+	if err := p.InitProxy(service); err != nil {
+		panic(err)
 	}
+}
 
-	ptrVal := reflect.ValueOf(service)
-	ptrTyp := reflect.TypeOf(service)
+// newRemoteProxy 返回addr对应的远端代理，不存在时创建，开启指标统计时同时注册连接池的指标
+// 同一个地址的InitProxy以及NewCaller共享一个代理，避免每次调用都创建新的连接池
+func (p *ProxyConstructor) newRemoteProxy(addr string) *RemoteProxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if proxy, ok := p.proxies[addr]; ok {
+		return proxy
+	}
+
+	proxy := newRemoteProxy(addr, p.dial)
 
-	if ptrVal.Kind() != reflect.Pointer && ptrVal.Kind() != reflect.Struct {
-		return errors.New("micro：入参必须为结构体指针且为一级指针")
+	if p.proxies == nil {
+		p.proxies = make(map[string]*RemoteProxy, 4)
 	}
+	p.proxies[addr] = proxy
+
+	if p.registry != nil {
+		if client, ok := proxy.client.(*DefaultClient); ok {
+			if pool, ok := client.pool.(*ConnPool[*clientConn]); ok {
+				proxy.onClose = registerPoolMetrics(p.registry, addr, pool)
+			}
+		}
+	}
+
+	return proxy
+}
+
+// Close 关闭所有由当前ProxyConstructor创建的代理持有的连接，之后发起的调用都会失败
+func (p *ProxyConstructor) Close() error {
+	p.mu.Lock()
+	proxies := p.proxies
+	p.proxies = nil
+	p.mu.Unlock()
+
+	var err error
+	for _, proxy := range proxies {
+		if e := proxy.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// setFuncField 校验service并将函数属性绑定到proxy
+func (p *ProxyConstructor) setFuncField(service Service, proxy Proxy) error {
+	methods, err := p.resolveMethods(service)
+	if err != nil {
+		return err
+	}
+
+	p.bindFuncFields(service, proxy, methods)
+
+	return nil
+}
+
+// resolveMethods 校验service，返回每个函数属性的调用选项，分组以及版本范围来自service的ServiceInfo
+func (p *ProxyConstructor) resolveMethods(service Service) (map[string]*methodOptions, error) {
+	methods, err := p.validateService(service)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info := service.Info()
+
+	versionRange, err := semver.ParseRange(info.Version)
+	if err != nil {
+		return nil, fmt.Errorf("micro：服务 %s 的版本范围不合法, %w", info.ServiceName, err)
+	}
+
+	for _, opts := range methods {
+		opts.group = info.Group
+		opts.version = info.Version
+		opts.versionRange = versionRange
+	}
+
+	return methods, nil
+}
+
+// bindFuncFields 篡改已经校验过的函数属性，调用时通过proxy发起请求
+func (p *ProxyConstructor) bindFuncFields(service Service, proxy Proxy, methods map[string]*methodOptions) {
+	ptrVal := reflect.ValueOf(service)
+	ptrTyp := reflect.TypeOf(service)
 
 	val := ptrVal.Elem()
 	typ := ptrTyp.Elem()
@@ -82,13 +242,22 @@ func (p ProxyConstructor) setFuncField(service Service, proxy Proxy) error {
 			continue
 		}
 
+		opts, ok := methods[fdTyp.Name]
+
+		if !ok {
+			// 通过rpc:"-"忽略的属性
+			continue
+		}
+
 		if fd.CanSet() {
 
+			resTyp := fdTyp.Type.Out(0).Elem()
+
 			// 定义函数进行篡改
 			fn := func(args []reflect.Value) (results []reflect.Value) {
 
 				// 根据返回值类型，实例化返回值
-				res := reflect.New(fdTyp.Type.Out(0).Elem())
+				res := reflect.New(resTyp)
 
 				ctx, ok := args[0].Interface().(context.Context)
 
@@ -97,110 +266,223 @@ func (p ProxyConstructor) setFuncField(service Service, proxy Proxy) error {
 					return []reflect.Value{res, reflect.ValueOf(err)}
 				}
 
-				// 将参数进行序列化
-				arg, err := p.serializer.Serialize(args[1].Interface())
+				err := p.call(ctx, proxy, service.Info().ServiceName, opts, args[1].Interface(), res.Interface())
 
 				if err != nil {
 					return []reflect.Value{res, reflect.ValueOf(err)}
 				}
 
-				// 构造调用信息
-				req := &message.Request{
-					RequestHeader: message.RequestHeader{
-						Header: message.Header{
-							Serializer: p.serializer.Code(),
-						},
-						ServiceName: service.Info().ServiceName,
-						MethodName:  fdTyp.Name,
-					},
-					Data: arg,
-				}
+				return []reflect.Value{res, reflect.Zero(errorType)}
+			}
 
-				if cTyp, ok := compress.EnableCompress(ctx); ok {
-					compressor, ok := p.compressors[cTyp]
-					if !ok {
-						err = errors.New("micro：找不到对应的压缩算法")
-						return []reflect.Value{res, reflect.ValueOf(err)}
-					}
-					req.Data, err = compressor.Compress(req.Data)
-					if err != nil {
-						return []reflect.Value{res, reflect.ValueOf(err)}
-					}
-					req.Compressor = uint8(cTyp)
-				}
+			f := reflect.MakeFunc(fd.Type(), fn)
+			// 开始篡改属性
+			fd.Set(f)
+		}
 
-				meta := make(map[string]string, 4)
+	}
+}
 
-				if oneway := isOneway(ctx); oneway {
-					meta["sys_oneway"] = "true"
-				}
+// call 完成一次远程调用的完整流程：序列化、压缩、构造元数据、请求服务端、解压缩以及反序列化
+// 代理桩函数以及泛型调用API都复用这套流程，保证两种调用风格的行为一致
+func (p *ProxyConstructor) call(ctx context.Context, proxy Proxy, serviceName string, opts *methodOptions, arg any, res any) (err error) {
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
 
-				if deadline, ok := ctx.Deadline(); ok {
-					dl := deadline.UnixMilli()
-					meta["sys_timeout"] = strconv.FormatInt(dl, 10)
-				}
+	if opts.oneway {
+		ctx = OnewayContext(ctx)
+	}
 
-				req.Meta = meta
+	start := time.Now()
+	stats := newCallStats()
 
-				var resp *message.Response
+	// 没有配置tracer时span为nil，所有操作都是空操作
+	ctx, span := p.tracer.Start(ctx, spanName(serviceName, opts.name), trace.SpanKindClient)
+	msgID := p.nextMsgID()
 
-				resC := make(chan struct{})
+	defer func() {
+		setStatsAttributes(span, stats)
+		span.RecordError(err)
+		span.End()
+		p.metrics.observe(serviceName, opts.name, start, stats, err)
+		p.log(proxy, serviceName, opts.name, msgID, start, err)
+	}()
 
-				go func() {
-					// 请求服务端，并获得响应
-					resp, err = proxy.Invoke(ctx, req)
-					select {
-					case resC <- struct{}{}:
-					default:
-					}
-				}()
+	serializer := p.serializer
 
-				select {
-				case <-ctx.Done():
-					return []reflect.Value{res, reflect.ValueOf(ctx.Err())}
-				case <-resC:
+	if opts.serializer != nil {
+		serializer = opts.serializer
+	}
 
-				}
+	// 将参数进行序列化
+	data, err := serializer.Serialize(arg)
 
-				if err != nil {
-					return []reflect.Value{res, reflect.ValueOf(err)}
-				}
+	if err != nil {
+		return err
+	}
 
-				// 将返回结果进行反序列化，构造返回值
-				if resData := resp.Data; resData != nil && len(resData) > 0 {
-					// 判断用户是否指定压缩算法，有则获取压缩算法进行解压缩操作
-					if cTyp, ok := compress.EnableCompress(ctx); ok {
-						compressor, ok := p.compressors[cTyp]
-						if !ok {
-							err = errors.New("micro：找不到对应的压缩算法")
-							return []reflect.Value{res, reflect.ValueOf(err)}
-						}
-						resData, err = compressor.Decompress(resData)
-						if err != nil {
-							return []reflect.Value{res, reflect.ValueOf(err)}
-						}
-					}
-					err = p.serializer.Deserialize(resData, res.Interface())
-				}
+	stats.reqSize = len(data)
+
+	// 构造调用信息
+	req := &message.Request{
+		RequestHeader: message.RequestHeader{
+			Header: message.Header{
+				MessageId:  msgID,
+				Serializer: serializer.Code(),
+			},
+			ServiceName: serviceName,
+			MethodName:  opts.name,
+		},
+		Data: data,
+	}
 
-				if err != nil {
-					return []reflect.Value{res, reflect.ValueOf(err)}
-				}
+	// 调用时通过ctx指定的压缩算法优先于tag指定的压缩算法
+	cTyp, ok := compress.EnableCompress(ctx)
 
-				if resp.Error != "" {
-					err = errors.New(resp.Error)
-					return []reflect.Value{res, reflect.ValueOf(err)}
-				}
+	if !ok {
+		cTyp = opts.compressor
+	}
+
+	var compressor compress.Compressor
 
-				return []reflect.Value{res, reflect.Zero(reflect.TypeOf(new(error)).Elem())}
+	if cTyp != 0 {
+		compressor, ok = p.compressors[cTyp]
+		if !ok {
+			return errors.New("micro：找不到对应的压缩算法")
+		}
+		req.Data, err = compressor.Compress(req.Data)
+		if err != nil {
+			return err
+		}
+		req.Compressor = uint8(cTyp)
+	}
+
+	meta := make(map[string]string, 4)
+
+	// 用户通过metadata.AppendOutgoing设置的元数据，不允许使用框架保留的key
+	if md, ok := metadata.FromOutgoing(ctx); ok {
+		for k, v := range md {
+			if err = metadata.Validate(k, v); err != nil {
+				return err
 			}
+			meta[k] = v
+		}
+	}
 
-			f := reflect.MakeFunc(fd.Type(), fn)
-			// 开始篡改属性
-			fd.Set(f)
+	if oneway := isOneway(ctx); oneway {
+		meta["sys_oneway"] = "true"
+	}
+
+	if opts.group != "" {
+		meta[groupKey] = opts.group
+	}
+
+	if opts.version != "" {
+		meta[versionKey] = opts.version
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		dl := deadline.UnixMilli()
+		meta["sys_timeout"] = strconv.FormatInt(dl, 10)
+	}
+
+	// 即使没有配置tracer，也需要将上游服务的链路信息传递给下游服务
+	trace.Inject(ctx, meta)
+
+	req.Meta = meta
+
+	if err = p.appendCredentials(ctx, req); err != nil {
+		return err
+	}
+
+	stats.reqWireSize = len(req.Data)
+
+	setRequestAttributes(span, req)
+
+	// 请求服务端，并获得响应
+	// 超时以及取消由Proxy负责处理，远端代理会将ctx的过期时间设置到连接上
+	resp, err := p.invoke(ctx, proxy, opts, req)
+
+	// oneway调用发送成功即返回，不等待服务端的处理结果
+	if err != nil || req.IsOneway() {
+		return err
+	}
+
+	stats.respWireSize = len(resp.Data)
+
+	if resp.Error == "" {
+		if err = checkVersion(serviceName, opts, resp.Meta[versionKey]); err != nil {
+			return err
 		}
+	}
+
+	// 将返回结果进行反序列化，构造返回值
+	if resData := resp.Data; resData != nil && len(resData) > 0 {
+		// 服务端使用请求的压缩算法压缩响应，有则进行解压缩操作
+		if compressor != nil {
+			resData, err = compressor.Decompress(resData)
+			if err != nil {
+				return err
+			}
+		}
+		stats.respSize = len(resData)
+		err = serializer.Deserialize(resData, res)
+	}
+
+	if err != nil {
+		return err
+	}
 
+	if resp.Error != "" {
+		return responseError(resp)
 	}
 
 	return nil
 }
+
+func (p *ProxyConstructor) nextMsgID() uint32 {
+	return nextMessageID(&p.msgID)
+}
+
+// log 输出一次调用的结果
+func (p *ProxyConstructor) log(proxy Proxy, serviceName, methodName string, msgID uint32, start time.Time, err error) {
+	kv := []any{
+		logger.KeyService, serviceName,
+		logger.KeyMethod, methodName,
+		logger.KeyMessageID, msgID,
+		logger.KeyLatency, time.Since(start),
+	}
+
+	if addr, ok := proxy.(interface{ Addr() string }); ok {
+		kv = append(kv, logger.KeyPeer, addr.Addr())
+	}
+
+	if err != nil {
+		p.logger.Debug("调用失败", append(kv, logger.KeyError, err)...)
+		return
+	}
+
+	p.logger.Debug("调用成功", kv...)
+}
+
+// invoke 请求服务端，幂等的方法在请求失败时会按照配置的次数进行重试
+// 只有请求本身失败才会重试，服务端返回的业务错误以及ctx过期都不会重试
+// 重试前重新生成凭证，避免带有nonce的签名被服务端当作重放拒绝
+func (p *ProxyConstructor) invoke(ctx context.Context, proxy Proxy, opts *methodOptions, req *message.Request) (*message.Response, error) {
+	resp, err := proxy.Invoke(ctx, req)
+
+	for i := 0; i < opts.retries && err != nil; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		if err = p.appendCredentials(ctx, req); err != nil {
+			return nil, err
+		}
+		resp, err = proxy.Invoke(ctx, req)
+	}
+
+	return resp, err
+}
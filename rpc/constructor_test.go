@@ -1,12 +1,15 @@
-package rpc
+package rpc_test
 
 import (
 	"context"
 	"errors"
+	"github.com/uzziahlin/transport/rpc"
 	"github.com/uzziahlin/transport/rpc/compress"
-	"github.com/uzziahlin/transport/rpc/errs"
+	"github.com/uzziahlin/transport/rpc/message"
 	"github.com/uzziahlin/transport/rpc/proto/user_service/gen"
+	"github.com/uzziahlin/transport/rpc/rpctest"
 	"github.com/uzziahlin/transport/rpc/serialize/proto"
+	"github.com/uzziahlin/transport/rpc/status"
 
 	"testing"
 	"time"
@@ -17,162 +20,156 @@ import (
 )
 
 func TestProxyConstructor_InitProxy(t *testing.T) {
+	t.Parallel()
 
-	endpoint := NewEndPoint(":8081")
+	endpoint := rpc.NewEndPoint("")
 
-	endpoint.Register(&UserServiceImpl{})
+	endpoint.Register(&rpc.UserServiceImpl{})
 
-	go func() {
-		err := endpoint.Startup()
-		require.NoError(t, err)
-	}()
+	// 走真实的tcp连接，其余用例使用内存连接
+	server := rpctest.NewServer(t, endpoint)
 
-	time.Sleep(3 * time.Second)
+	constructor := server.NewConstructor()
 
-	constructor := NewProxyConstructor()
-
-	userService := &UserService{}
+	userService := &rpc.UserService{}
 
 	err := constructor.InitProxy(userService)
 
 	require.NoError(t, err)
 
-	resp, err := userService.GetById(context.Background(), &UserReq{
+	resp, err := userService.GetById(context.Background(), &rpc.UserReq{
 		Id: "this is the user id",
 	})
 
 	require.NoError(t, err)
 
-	// fmt.Print(resp.Content)
-	t.Log(resp.Content)
+	assert.Equal(t, "response: this is the user id", resp.Content)
 }
 
 func TestProxyConstructor_Oneway(t *testing.T) {
+	t.Parallel()
+
+	errC := make(chan error, 1)
 
-	endpoint := NewEndPoint(":8081")
+	endpoint := rpc.NewEndPoint("", rpc.WithOnewayErrorHandler(func(ctx context.Context, req *message.Request, err error) {
+		errC <- err
+	}))
 
-	endpoint.Register(&UserServiceImpl{})
+	service := &rpc.UserServiceNotify{
+		Received: make(chan string, 2),
+		Err:      errors.New("this is the err"),
+	}
 
-	go func() {
-		err := endpoint.Startup()
-		require.NoError(t, err)
-	}()
+	endpoint.Register(service)
 
-	time.Sleep(3 * time.Second)
+	server := rpctest.NewInMemoryServer(t, endpoint)
 
-	constructor := NewProxyConstructor()
+	constructor := server.NewConstructor()
 
-	userService := &UserService{}
+	userService := &rpc.UserService{}
 
 	err := constructor.InitProxy(userService)
 
 	require.NoError(t, err)
 
-	ctx := OnewayContext(context.Background())
+	ctx := rpc.OnewayContext(context.Background())
 
-	resp, err := userService.GetById(ctx, &UserReq{
+	// 发送成功即返回nil，服务方法的错误只会交给服务端的回调
+	_, err = userService.GetById(ctx, &rpc.UserReq{
 		Id: "this is the user id",
 	})
 
-	assert.Equal(t, errs.ErrOneway, err)
+	require.NoError(t, err)
+	assert.Equal(t, "this is the user id", <-service.Received)
+	assert.Equal(t, errors.New("this is the err"), <-errC)
+
+	// 服务端没有写回响应，同一个连接上的下一次调用不会读到oneway请求的响应
+	_, err = userService.GetById(context.Background(), &rpc.UserReq{
+		Id: "the second id",
+	})
 
-	// fmt.Print(resp.Content)
-	t.Log(resp.Content)
+	assert.Equal(t, errors.New("this is the err"), err)
+	assert.Equal(t, "the second id", <-service.Received)
 }
 
 func TestProxyConstructor_Timeout(t *testing.T) {
+	t.Parallel()
 
-	endpoint := NewEndPoint(":8081")
-
-	endpoint.Register(&UserServiceTimeout{})
+	endpoint := rpc.NewEndPoint("")
 
-	go func() {
-		err := endpoint.Startup()
-		require.NoError(t, err)
-	}()
+	endpoint.Register(&rpc.UserServiceTimeout{})
 
-	time.Sleep(3 * time.Second)
+	server := rpctest.NewInMemoryServer(t, endpoint)
 
-	constructor := NewProxyConstructor()
+	constructor := server.NewConstructor()
 
-	userService := &UserService{}
+	userService := &rpc.UserService{}
 
 	err := constructor.InitProxy(userService)
 
 	require.NoError(t, err)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 
-	resp, err := userService.GetById(ctx, &UserReq{
+	_, err = userService.GetById(ctx, &rpc.UserReq{
 		Id: "this is the user id",
 	})
 
-	assert.Equal(t, context.DeadlineExceeded, err)
-
-	// fmt.Print(resp.Content)
-	t.Log(resp.Content)
+	// 服务端与客户端使用同一个过期时间，可能是客户端的ctx先过期，也可能是服务端先返回超时的错误
+	assert.Equal(t, status.DeadlineExceeded, status.CodeOf(err))
 }
 
 func TestProxyConstructor_Err(t *testing.T) {
+	t.Parallel()
 
-	endpoint := NewEndPoint(":8081")
+	endpoint := rpc.NewEndPoint("")
 
-	service := &UserServiceErr{}
+	service := &rpc.UserServiceErr{}
 
 	service.Msg = "this is the msg"
 	service.Err = "this is the err"
 
 	endpoint.Register(service)
 
-	go func() {
-		err := endpoint.Startup()
-		require.NoError(t, err)
-	}()
+	server := rpctest.NewInMemoryServer(t, endpoint)
 
-	time.Sleep(3 * time.Second)
+	constructor := server.NewConstructor()
 
-	constructor := NewProxyConstructor()
-
-	userService := &UserService{}
+	userService := &rpc.UserService{}
 
 	err := constructor.InitProxy(userService)
 
 	require.NoError(t, err)
 
-	resp, err := userService.GetById(context.Background(), &UserReq{
+	resp, err := userService.GetById(context.Background(), &rpc.UserReq{
 		Id: "this is the user id",
 	})
 
 	assert.Equal(t, errors.New("this is the err"), err)
 
-	// fmt.Print(resp.Content)
-	t.Log(resp.Content)
+	assert.Equal(t, "this is the msg", resp.Content)
 }
 
 func TestProxyConstructor_WithProtoSerializer(t *testing.T) {
+	t.Parallel()
 
-	endpoint := NewEndPoint(":8081")
+	endpoint := rpc.NewEndPoint("")
 
 	endpoint.RegisterSerializer(&proto.Serializer{})
 
-	service := &UserServiceProto{}
+	service := &rpc.UserServiceProto{}
 
 	service.Msg = "this is the msg"
 	service.Err = "this is the err"
 
 	endpoint.Register(service)
 
-	go func() {
-		err := endpoint.Startup()
-		require.NoError(t, err)
-	}()
-
-	time.Sleep(3 * time.Second)
+	server := rpctest.NewInMemoryServer(t, endpoint)
 
-	constructor := NewProxyConstructor(WithSerializer(&proto.Serializer{}))
+	constructor := server.NewConstructor(rpc.WithSerializer(&proto.Serializer{}))
 
-	userService := &UserService{}
+	userService := &rpc.UserService{}
 
 	err := constructor.InitProxy(userService)
 
@@ -184,31 +181,26 @@ func TestProxyConstructor_WithProtoSerializer(t *testing.T) {
 
 	assert.Equal(t, errors.New("this is the err"), err)
 
-	// fmt.Print(resp.Content)
-	t.Log(resp.Msg)
+	assert.Equal(t, "this is the msg", resp.Msg)
 }
 
 func TestProxyConstructor_WithCompressor(t *testing.T) {
+	t.Parallel()
 
-	endpoint := NewEndPoint(":8081")
+	endpoint := rpc.NewEndPoint("")
 
-	service := &UserServiceProto{}
+	service := &rpc.UserServiceProto{}
 
 	service.Msg = "this is the msg"
 	service.Err = "this is the err"
 
 	endpoint.Register(service)
 
-	go func() {
-		err := endpoint.Startup()
-		require.NoError(t, err)
-	}()
-
-	time.Sleep(3 * time.Second)
+	server := rpctest.NewInMemoryServer(t, endpoint)
 
-	constructor := NewProxyConstructor(WithSerializer(&proto.Serializer{}))
+	constructor := server.NewConstructor(rpc.WithSerializer(&proto.Serializer{}))
 
-	userService := &UserService{}
+	userService := &rpc.UserService{}
 
 	err := constructor.InitProxy(userService)
 
@@ -222,96 +214,5 @@ func TestProxyConstructor_WithCompressor(t *testing.T) {
 
 	assert.Equal(t, errors.New("this is the err"), err)
 
-	// fmt.Print(resp.Content)
-	t.Log(resp.Msg)
-}
-
-type UserService struct {
-	GetById func(ctx context.Context, req *UserReq) (*UserResp, error)
-
-	GetByIdProto func(ctx context.Context, req *gen.UserReq) (*gen.UserResp, error)
-}
-
-type UserReq struct {
-	Id string
-}
-
-type UserResp struct {
-	Content string
-}
-
-func (u UserService) Info() ServiceInfo {
-	return ServiceInfo{
-		ServiceName: "user-service",
-		Addr:        "localhost:8081",
-	}
-}
-
-type UserServiceImpl struct {
-}
-
-func (u *UserServiceImpl) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
-	id := req.Id
-
-	return &UserResp{
-		Content: "response: " + id,
-	}, nil
-}
-
-func (u *UserServiceImpl) Info() ServiceInfo {
-	return ServiceInfo{
-		ServiceName: "user-service",
-	}
-}
-
-type UserServiceTimeout struct {
-	Msg string
-	Err string
-}
-
-func (u *UserServiceTimeout) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
-	time.Sleep(3 * time.Second)
-	return &UserResp{
-		Content: u.Msg,
-	}, errors.New(u.Err)
-}
-
-func (u *UserServiceTimeout) Info() ServiceInfo {
-	return ServiceInfo{
-		ServiceName: "user-service",
-	}
-}
-
-type UserServiceErr struct {
-	Msg string
-	Err string
-}
-
-func (u *UserServiceErr) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
-	return &UserResp{
-		Content: u.Msg,
-	}, errors.New(u.Err)
-}
-
-func (u *UserServiceErr) Info() ServiceInfo {
-	return ServiceInfo{
-		ServiceName: "user-service",
-	}
-}
-
-type UserServiceProto struct {
-	Msg string
-	Err string
-}
-
-func (u *UserServiceProto) GetByIdProto(ctx context.Context, req *gen.UserReq) (*gen.UserResp, error) {
-	return &gen.UserResp{
-		Msg: u.Msg,
-	}, errors.New(u.Err)
-}
-
-func (u *UserServiceProto) Info() ServiceInfo {
-	return ServiceInfo{
-		ServiceName: "user-service",
-	}
+	assert.Equal(t, "this is the msg", resp.Msg)
 }
@@ -2,23 +2,123 @@ package rpc
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"github.com/uzziahlin/transport/logger"
+	"github.com/uzziahlin/transport/rpc/auth"
 	"github.com/uzziahlin/transport/rpc/compress"
 	"github.com/uzziahlin/transport/rpc/compress/gzip"
 	"github.com/uzziahlin/transport/rpc/compress/zip"
 	"github.com/uzziahlin/transport/rpc/errs"
 	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/metadata"
+	"github.com/uzziahlin/transport/rpc/metrics"
+	"github.com/uzziahlin/transport/rpc/semver"
 	"github.com/uzziahlin/transport/rpc/serialize"
 	"github.com/uzziahlin/transport/rpc/serialize/json"
 	"github.com/uzziahlin/transport/rpc/serialize/proto"
-	"log"
+	"github.com/uzziahlin/transport/rpc/status"
+	"github.com/uzziahlin/transport/rpc/trace"
+	"io"
 	"net"
+	"os"
 	"reflect"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 )
 
-func NewEndPoint(addr string) *EndPoint {
+type EndPointOpt func(e *EndPoint)
+
+// WithForwardMeta 指定需要透传的元数据key
+// 服务端收到的请求中如果带有这些key，在处理请求的过程中发起的下游调用会自动携带这些元数据
+func WithForwardMeta(keys ...string) EndPointOpt {
+	return func(e *EndPoint) {
+		e.forwardKeys = append(e.forwardKeys, keys...)
+	}
+}
+
+// WithServerTracer 设置tracer，每次处理请求都会创建一个服务端span
+func WithServerTracer(tracer *trace.Tracer) EndPointOpt {
+	return func(e *EndPoint) {
+		e.tracer = tracer
+	}
+}
+
+// WithServerMetrics 开启服务端的调用指标统计，指标名以rpc_server_为前缀
+func WithServerMetrics(reg *metrics.Registry) EndPointOpt {
+	return func(e *EndPoint) {
+		e.metrics = newRpcMetrics(reg, "server")
+		e.metrics.panics = reg.Counter("rpc_server_panics_total",
+			"Total number of panics recovered in service methods.",
+			"service", "method")
+	}
+}
+
+// WithServerLogger 设置服务端的logger，默认为logger.Default()
+func WithServerLogger(l logger.Logger) EndPointOpt {
+	return func(e *EndPoint) {
+		e.logger = l
+	}
+}
+
+// WithOnewayWorkers 设置同时处理oneway请求的g的数量上限，默认为defaultOnewayWorkers
+// 达到上限之后，连接会暂停读取新的请求，直到有oneway请求处理完毕
+func WithOnewayWorkers(n int) EndPointOpt {
+	return func(e *EndPoint) {
+		e.oneway = newWorkerPool(n)
+	}
+}
+
+// OnewayErrorHandler oneway请求处理失败时的回调，客户端不会收到任何响应，错误只能在服务端处理
+type OnewayErrorHandler func(ctx context.Context, req *message.Request, err error)
+
+// WithOnewayErrorHandler 设置oneway请求处理失败时的回调，默认输出Error级别的日志
+func WithOnewayErrorHandler(h OnewayErrorHandler) EndPointOpt {
+	return func(e *EndPoint) {
+		e.onewayErrHandler = h
+	}
+}
+
+// WithMaxConcurrentRequests 设置所有连接同时处理的请求数量上限，默认为defaultMaxConcurrentRequests
+// 达到上限之后，连接会暂停读取新的请求，直到有请求处理完毕
+func WithMaxConcurrentRequests(n int) EndPointOpt {
+	return func(e *EndPoint) {
+		e.workers = newWorkerPool(n)
+	}
+}
+
+// WithMaxConcurrentRequestsPerConn 设置单个连接同时处理的请求数量上限，默认为defaultMaxConcurrentRequestsPerConn
+//
+// 上限为1时，同一个连接上的请求严格按照到达的顺序处理，响应的顺序与请求的顺序一致；
+// 上限大于1时，同一个连接上的请求并发处理，响应按照处理完成的顺序写回，客户端需要通过消息id匹配响应。
+// 无论哪种模式，每个响应都会完整地写入连接，不会与其他响应交错。
+// oneway请求不受该上限约束，由WithOnewayWorkers单独控制
+func WithMaxConcurrentRequestsPerConn(n int) EndPointOpt {
+	return func(e *EndPoint) {
+		e.connConcurrency = n
+	}
+}
+
+// WithMaxHealthWatchers 设置同时处理的健康检查Watch请求的数量上限，默认为defaultMaxHealthWatchers
+// Watch请求最长会等待30秒，所以不占用WithMaxConcurrentRequests以及WithMaxConcurrentRequestsPerConn的名额，
+// 避免长时间等待的Watch请求阻塞业务请求。达到上限之后新的Watch请求立即返回status.ResourceExhausted
+func WithMaxHealthWatchers(n int) EndPointOpt {
+	return func(e *EndPoint) {
+		e.watchers = newWorkerPool(n)
+	}
+}
+
+const (
+	defaultOnewayWorkers                = 64
+	defaultMaxConcurrentRequests        = 1024
+	defaultMaxConcurrentRequestsPerConn = 16
+	defaultMaxHealthWatchers            = 256
+)
+
+func NewEndPoint(addr string, opts ...EndPointOpt) *EndPoint {
 
 	jsonS := &json.Serializer{}
 	protoS := &proto.Serializer{}
@@ -36,37 +136,81 @@ func NewEndPoint(addr string) *EndPoint {
 			gzipC.Code(): gzipC,
 			zipC.Code():  zipC,
 		},
-		services:    make(map[string]reflectionStub, 16),
-		reqEncoder:  &message.DefaultRequestEncoder{},
-		respEncoder: &message.DefaultResponseEncoder{},
+		services:        make(map[string][]*reflectionStub, 16),
+		reqEncoder:      &message.DefaultRequestEncoder{},
+		respEncoder:     &message.DefaultResponseEncoder{},
+		logger:          logger.Default(),
+		oneway:          newWorkerPool(defaultOnewayWorkers),
+		workers:         newWorkerPool(defaultMaxConcurrentRequests),
+		watchers:        newWorkerPool(defaultMaxHealthWatchers),
+		connConcurrency: defaultMaxConcurrentRequestsPerConn,
+		conns:           make(map[*serverConn]struct{}, 16),
+		health:          newHealthState(),
+		ranges:          newRangeCache(maxCachedRanges),
+	}
+
+	for _, opt := range opts {
+		opt(ep)
 	}
 
 	server := NewServer(addr, ep.handler)
 
+	if ep.transport != nil {
+		server.listen = ep.transport.Listen
+	} else if ep.socketMode != 0 {
+		server.listen = (&NetTransport{SocketMode: ep.socketMode}).Listen
+	}
+	server.RegisterOnShutdown(ep.drain)
+	server.tlsConfig = ep.tlsConfig
+
 	ep.server = server
 
 	return ep
 }
 
 type EndPoint struct {
-	services    map[string]reflectionStub
+	servicesMu sync.RWMutex
+	// services 同一个服务名可以注册多个分组以及版本的实例
+	services    map[string][]*reflectionStub
 	serializers map[uint8]serialize.Serializer
 	compressors map[uint8]compress.Compressor
 	read        Reader
 	server      *Server
 	reqEncoder  message.RequestEncoder
 	respEncoder message.ResponseEncoder
-}
-
-func (e *EndPoint) Register(service Service) {
-	serviceName := service.Info().ServiceName
-
-	e.services[serviceName] = reflectionStub{
-		s:           service,
-		value:       reflect.ValueOf(service),
-		serializers: e.serializers,
-		compressors: e.compressors,
-	}
+	forwardKeys []string
+	tracer      *trace.Tracer
+	metrics     *rpcMetrics
+	logger      logger.Logger
+
+	oneway           *workerPool
+	onewayErrHandler OnewayErrorHandler
+	workers          *workerPool
+	watchers         *workerPool
+	connConcurrency  int
+	panicHandler     PanicHandler
+	tlsConfig        *tls.Config
+	authenticator    auth.Authenticator
+	policy           *auth.Policy
+
+	transport  Transport
+	socketMode os.FileMode
+
+	registryMu        sync.Mutex
+	registryListeners []RegistryListener
+
+	// ranges 请求中的版本范围解析之后的缓存
+	ranges *rangeCache
+
+	health     *healthState
+	probes     []healthProbe
+	probesOnce sync.Once
+	probesErr  error
+
+	connsMu sync.Mutex
+	conns   map[*serverConn]struct{}
+	// goAway 不为nil代表优雅关闭已经开始，之后加入的连接立即进入drain状态
+	goAway []byte
 }
 
 func (e *EndPoint) RegisterSerializer(serializer serialize.Serializer) {
@@ -77,173 +221,524 @@ func (e *EndPoint) RegisterCompressor(compressor compress.Compressor) {
 	e.compressors[compressor.Code()] = compressor
 }
 
+// handler 循环读取连接上的请求，并交给worker池并发处理，响应由serverConn串行写回
 func (e *EndPoint) handler(conn net.Conn) {
+	sc := newServerConn(conn, e.connConcurrency)
 
-	for {
-		// 解码出请求信息
-		data, err := e.read(conn)
+	e.addConn(sc)
 
-		var (
-			res    *message.Response
-			req    *message.Request
-			cancel context.CancelFunc = func() {}
-		)
+	defer func() {
+		e.removeConn(sc)
+		_ = sc.Close()
+	}()
 
-		ctx := context.Background()
+	p := &Peer{
+		Addr: conn.RemoteAddr(),
+	}
+
+	l := e.logger.With(logger.KeyPeer, p.Addr.String())
+
+	if err := handshake(conn, p); err != nil {
+		l.Error("TLS握手失败", logger.KeyError, err)
+		return
+	}
+
+	for {
+		// 解码出请求信息
+		data, err := e.read(sc)
 
 		if err != nil {
-			log.Printf("请求数据解码错误")
-			goto RESP
+			// 连接被优雅关闭流程关闭时，读取错误是预期内的
+			if errors.Is(err, io.EOF) || sc.isClosed() {
+				l.Debug("连接已关闭")
+			} else {
+				l.Error("请求数据读取错误", logger.KeyError, err)
+			}
+			return
 		}
 
 		// 反序列化请求调用信息，应该是Request结构
-		req, err = e.reqEncoder.Decode(data)
+		req, err := e.reqEncoder.Decode(data)
 
 		if err != nil {
-			log.Printf("请求数据反序列化错误")
-			goto RESP
-		}
-
-		if meta := req.Meta; meta != nil {
-			dl, ok := meta["sys_timeout"]
-			if ok {
-				deadline, err := strconv.ParseInt(dl, 10, 64)
-				if err != nil {
-					log.Printf("时间格式不对")
-					goto RESP
-				}
-				// 如果上游服务带了deadline，说明链路有过期时间，应该重建context
-				ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(deadline))
-			}
+			l.Error("请求数据反序列化错误", logger.KeyError, err)
+			return
 		}
 
-		res, err = e.Invoke(ctx, req)
+		// 连接已经因为优雅关闭而关闭，请求不会被处理，客户端收到GOAWAY之后会重试
+		if !sc.begin() {
+			return
+		}
+
+		ctx := NewPeerContext(context.Background(), p)
+
+		// oneway请求不写回响应，异步处理之后直接读取下一个请求
+		if req.IsOneway() {
+			e.serveOneway(ctx, req, l, sc.end)
+			continue
+		}
 
-		if ctx.Err() != nil {
-			log.Printf("请求超时了，不用给客户端返回了")
-			err = ctx.Err()
+		// 健康检查的Watch请求会长时间等待，不占用处理业务请求的名额
+		if isHealthWatch(req) {
+			e.serveWatch(ctx, sc, req, l)
+			continue
 		}
 
-		cancel()
+		// 先占用连接的名额再交给worker池，达到上限时暂停读取新的请求
+		sc.acquire()
 
-	RESP:
-		if err != nil {
-			log.Printf(err.Error())
-			res.Error = err.Error()
+		e.workers.Go(func() {
+			defer sc.release()
+			e.reply(sc, req, e.serve(ctx, req, l), l)
+		})
+	}
+}
+
+// serveWatch 在单独的worker池中处理健康检查的Watch请求，达到上限时立即返回status.ResourceExhausted
+func (e *EndPoint) serveWatch(ctx context.Context, sc *serverConn, req *message.Request, l logger.Logger) {
+	ok := e.watchers.TryGo(func() {
+		e.reply(sc, req, e.serve(ctx, req, l), l)
+	})
+
+	if ok {
+		return
+	}
+
+	res := &message.Response{}
+	res.MessageId = req.MessageId
+	setResponseError(res, status.New(status.ResourceExhausted, "micro：健康检查的Watch请求数量达到上限"))
+
+	// 写入可能阻塞，不能占用读取请求的循环
+	go e.reply(sc, req, res, l)
+}
+
+// reply 写回响应并结束请求，写入失败时关闭连接
+func (e *EndPoint) reply(sc *serverConn, req *message.Request, res *message.Response, l logger.Logger) {
+	err := sc.write(e.respEncoder.Encode(res))
+
+	sc.end()
+
+	if err != nil {
+		l.Error("响应写入错误", logger.KeyService, req.ServiceName, logger.KeyMethod, req.MethodName,
+			logger.KeyMessageID, req.MessageId, logger.KeyError, err)
+		// 关闭连接，让读取请求的循环退出
+		_ = sc.Close()
+	}
+}
+
+// addConn 记录连接，优雅关闭开始之后才加入的连接同样需要收到GOAWAY，否则Shutdown会一直等待到超时
+func (e *EndPoint) addConn(sc *serverConn) {
+	e.connsMu.Lock()
+	defer e.connsMu.Unlock()
+	e.conns[sc] = struct{}{}
+
+	if e.goAway != nil {
+		go sc.drain(e.goAway)
+	}
+}
+
+func (e *EndPoint) removeConn(sc *serverConn) {
+	e.connsMu.Lock()
+	defer e.connsMu.Unlock()
+	delete(e.conns, sc)
+}
+
+// drain 向所有连接发送GOAWAY帧，连接在处理中的请求结束之后关闭
+func (e *EndPoint) drain() {
+	// 先通知健康检查，负载均衡不再转发新的连接
+	e.health.drain()
+
+	goAway := e.respEncoder.Encode(&message.Response{
+		ResponseHeader: message.ResponseHeader{
+			Header: message.Header{
+				MessageId: message.GoAwayMessageId,
+			},
+		},
+	})
+
+	e.connsMu.Lock()
+	defer e.connsMu.Unlock()
+
+	e.goAway = goAway
+
+	// 客户端不读取数据时写入GOAWAY会阻塞，所以每个连接单独处理
+	for sc := range e.conns {
+		go sc.drain(goAway)
+	}
+}
+
+// serve 处理一个请求，处理过程中的错误会写入响应，并通过logger输出
+func (e *EndPoint) serve(ctx context.Context, req *message.Request, l logger.Logger) *message.Response {
+	l = l.With(logger.KeyService, req.ServiceName, logger.KeyMethod, req.MethodName, logger.KeyMessageID, req.MessageId)
+
+	res, err := e.serveWithDeadline(ctx, req)
+
+	if res == nil {
+		res = &message.Response{}
+	}
+
+	if err != nil {
+		l.Error("请求处理失败", logger.KeyError, err)
+		setResponseError(res, err)
+	} else if res.Error != "" {
+		l.Debug("服务方法返回错误", logger.KeyError, res.Error)
+	}
+
+	res.MessageId = req.MessageId
+
+	return res
+}
+
+// serveOneway 在oneway的worker池中处理请求，处理失败时调用onewayErrHandler，处理结束之后调用done
+func (e *EndPoint) serveOneway(ctx context.Context, req *message.Request, l logger.Logger, done func()) {
+	e.oneway.Go(func() {
+		defer done()
+
+		_, err := e.serveWithDeadline(ctx, req)
+
+		if err == nil {
+			return
 		}
 
-		resp := e.respEncoder.Encode(res)
+		if e.onewayErrHandler != nil {
+			e.onewayErrHandler(ctx, req, err)
+			return
+		}
+
+		l.Error("oneway请求处理失败", logger.KeyService, req.ServiceName, logger.KeyMethod, req.MethodName,
+			logger.KeyMessageID, req.MessageId, logger.KeyError, err)
+	})
+}
 
-		_, err = conn.Write(resp)
+// serveWithDeadline 如果上游服务带了deadline，说明链路有过期时间，应该重建context
+func (e *EndPoint) serveWithDeadline(ctx context.Context, req *message.Request) (*message.Response, error) {
+	if dl, ok := req.Meta["sys_timeout"]; ok {
+		deadline, err := strconv.ParseInt(dl, 10, 64)
 
 		if err != nil {
-			log.Printf("响应出错了")
-			continue
+			return nil, status.Newf(status.InvalidArgument, "micro：过期时间 %q 格式不对", dl)
 		}
 
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(deadline))
+		defer cancel()
+	}
+
+	res, err := e.Invoke(ctx, req)
+
+	if ctx.Err() != nil {
+		// 请求超时了，客户端已经不再等待响应
+		return nil, status.FromContextError(ctx.Err())
 	}
+
+	return res, err
+}
+
+// Invoke 处理一个请求并返回响应，处理过程中发生的panic会被恢复并转换为status.Internal错误
+func (e *EndPoint) Invoke(ctx context.Context, req *message.Request) (resp *message.Response, err error) {
+	// 服务方法的panic在invokeService中恢复，这里恢复认证、授权、链路追踪以及指标等其他环节的panic，
+	// 避免panic导致整个进程退出
+	defer func() {
+		if r := recover(); r != nil {
+			err = e.recoverPanic(ctx, req, r, "处理请求时发生panic")
+			if req.IsOneway() {
+				return
+			}
+			resp = &message.Response{}
+			setResponseError(resp, err)
+			err = nil
+		}
+	}()
+
+	return e.invoke(ctx, req)
 }
 
-func (e *EndPoint) Invoke(ctx context.Context, req *message.Request) (*message.Response, error) {
-	// 根据调用信息获取服务
-	service := e.services[req.ServiceName]
+// invoke 处理一个请求：认证以及授权、调用服务方法，并记录链路以及指标
+func (e *EndPoint) invoke(ctx context.Context, req *message.Request) (*message.Response, error) {
+	// 上游服务的链路信息作为服务端span的父span
+	ctx = trace.Extract(ctx, req.Meta)
+
+	ctx, span := e.tracer.Start(ctx, spanName(req.ServiceName, req.MethodName), trace.SpanKindServer)
+	defer span.End()
+
+	setRequestAttributes(span, req)
 
-	// 通过反射获取服务的相关方法信息
-	res, err := service.invoke(ctx, req)
+	start := time.Now()
+	stats := newCallStats()
+	stats.reqWireSize = len(req.Data)
 
-	var errStr string
+	ctx = e.metadataContext(ctx, req)
+
+	var (
+		res     []byte
+		version string
+		err     error
+	)
+
+	// 认证以及授权失败时不调用服务方法
+	ctx, err = e.authorize(ctx, req)
+	if err == nil {
+		res, version, err = e.invokeService(ctx, req, stats)
+	}
+
+	// oneway请求没有响应，错误直接返回给调用方
+	if !req.IsOneway() {
+		stats.respWireSize = len(res)
+	}
+
+	setStatsAttributes(span, stats)
+	span.RecordError(err)
+	if e.metrics != nil {
+		service, method := e.metricLabels(req)
+		e.metrics.observe(service, method, start, stats, err)
+	}
+
+	if req.IsOneway() {
+		return nil, err
+	}
+
+	resp := &message.Response{
+		Data: res,
+	}
 
 	if err != nil {
-		if errors.Is(err, errs.ErrOneway) {
-			return nil, err
+		setResponseError(resp, err)
+	} else if version != "" {
+		// 客户端据此校验处理请求的服务版本是否在期望的范围内
+		resp.Meta = map[string]string{versionKey: version}
+	}
+
+	return resp, nil
+}
+
+// invokeService 根据调用信息获取服务，再通过反射调用服务的方法
+// 服务方法发生panic时会被恢复并转换为status.Internal错误，连接可以继续使用
+// 同时返回处理请求的服务的版本号
+func (e *EndPoint) invokeService(ctx context.Context, req *message.Request, stats *callStats) (res []byte, version string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = e.recoverPanic(ctx, req, r, "服务方法发生panic")
 		}
-		errStr = err.Error()
+	}()
+
+	service, err := e.acquireService(req)
+
+	if err != nil {
+		return nil, "", err
 	}
 
-	return &message.Response{
-		ResponseHeader: message.ResponseHeader{
-			Error: errStr,
-		},
-		Data: res,
-	}, nil
+	defer service.inflight.Done()
 
+	res, err = service.invoke(ctx, req, stats)
+
+	return res, service.info.Version, err
 }
 
+// serviceNames 返回所有已经注册的服务名，按照字典序排序
+func (e *EndPoint) serviceNames() []string {
+	e.servicesMu.RLock()
+	defer e.servicesMu.RUnlock()
+
+	return e.serviceNamesLocked()
+}
+
+func (e *EndPoint) serviceNamesLocked() []string {
+	res := make([]string, 0, len(e.services))
+	for name := range e.services {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// metadataContext 将请求中的用户元数据放入ctx，服务方法可以通过metadata.FromIncoming读取
+// 同时将需要透传的key设置为下游调用的元数据
+func (e *EndPoint) metadataContext(ctx context.Context, req *message.Request) context.Context {
+	md := make(metadata.MD, len(req.Meta))
+
+	for k, v := range req.Meta {
+		if !metadata.IsReserved(k) {
+			md[k] = v
+		}
+	}
+
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	// 下游调用的元数据只包含透传的key，不继承ctx中原有的元数据
+	forward := make(metadata.MD, len(e.forwardKeys))
+
+	for _, k := range e.forwardKeys {
+		if v, ok := md[k]; ok {
+			forward[k] = v
+		}
+	}
+
+	return metadata.NewOutgoingContext(ctx, forward)
+}
+
+// Startup 监听地址并处理请求，监听成功之后才启动健康检查的探针
 func (e *EndPoint) Startup() error {
-	return e.server.Start()
+	listener, err := e.server.listen(e.server.addr)
+	if err != nil {
+		return err
+	}
+	return e.Serve(listener)
+}
+
+// Serve 在给定的listener上处理请求，适合由调用方自行创建listener的场景，例如监听随机端口
+// Close之后返回errs.ErrServerClosed
+func (e *EndPoint) Serve(listener net.Listener) error {
+	if err := e.startProbes(); err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	err := e.server.Serve(listener)
+
+	// 不是因为Shutdown或者Close退出时，探针不会再被关闭，需要在这里释放
+	if !errors.Is(err, errs.ErrServerClosed) {
+		e.closeProbes()
+	}
+
+	return err
+}
+
+// Shutdown 优雅关闭：停止监听，向所有连接发送GOAWAY帧通知客户端不要再发送新的请求，
+// 等待处理中的请求结束之后关闭连接。ctx过期时强制关闭所有连接并返回ctx.Err()
+func (e *EndPoint) Shutdown(ctx context.Context) error {
+	defer e.closeProbes()
+	return e.server.Shutdown(ctx)
+}
+
+// Close 立即停止监听并关闭所有连接，处理中的请求会失败
+func (e *EndPoint) Close() error {
+	defer e.closeProbes()
+	return e.server.Close()
 }
 
 type reflectionStub struct {
-	s           Service
-	value       reflect.Value
+	s       Service
+	info    ServiceInfo
+	version *semver.Version
+	value   reflect.Value
+	methods map[string]*serviceMethod
+	// methodNames 可以被远程调用的方法名，按照字典序排序
+	methodNames []string
+	// rejected 签名不符合要求、不能被远程调用的导出方法
+	rejected    []rejectedMethod
 	serializers map[uint8]serialize.Serializer
 	compressors map[uint8]compress.Compressor
+	// inflight 正在处理的请求，Unregister时等待其结束
+	inflight sync.WaitGroup
 }
 
-func (r *reflectionStub) invoke(ctx context.Context, req *message.Request) ([]byte, error) {
-	method := r.value.MethodByName(req.MethodName)
+// invoke 调用服务方法，并将请求以及响应压缩前的大小记录到stats
+func (r *reflectionStub) invoke(ctx context.Context, req *message.Request, stats *callStats) ([]byte, error) {
+	method, ok := r.methods[req.MethodName]
+
+	// 方法不存在，或者不是形如 func(context.Context, *Req) (*Resp, error) 的服务方法，例如Info
+	if !ok {
+		for _, m := range r.rejected {
+			if m.name == req.MethodName {
+				return nil, status.Newf(status.Unimplemented, "micro：服务 %q 的方法 %q 不能被远程调用, %s", req.ServiceName, req.MethodName, m.reason).
+					WithDetails(r.methodNames...)
+			}
+		}
+		return nil, status.Newf(status.Unimplemented, "micro：服务 %q 没有方法 %q", req.ServiceName, req.MethodName).
+			WithDetails(r.methodNames...)
+	}
 
 	var err error
 
 	if cTyp := req.Compressor; cTyp != 0 {
 		compressor, ok := r.compressors[cTyp]
 		if !ok {
-			return nil, errors.New("找不到相应的压缩算法支持")
+			return nil, unsupportedCompressor(cTyp, r.compressors)
 		}
 		req.Data, err = compressor.Decompress(req.Data)
 		if err != nil {
-			log.Printf("请求数据解压缩失败")
-			return nil, err
+			return nil, fmt.Errorf("micro：请求数据解压缩失败, %w", err)
 		}
 	}
 
-	serializer := r.serializers[req.Serializer]
+	stats.reqSize = len(req.Data)
+
+	serializer, ok := r.serializers[req.Serializer]
 
-	resPtr := reflect.New(method.Type().In(1).Elem())
+	if !ok {
+		return nil, status.Newf(status.Unimplemented, "micro：不支持的序列化协议 %d", req.Serializer).
+			WithDetails(codes(r.serializers)...)
+	}
 
-	err = serializer.Deserialize(req.Data, resPtr.Interface())
+	argPtr := reflect.New(method.argElem)
+
+	err = serializer.Deserialize(req.Data, argPtr.Interface())
 
 	if err != nil {
-		log.Printf("请求数据反序列化错误")
-		return nil, err
+		return nil, status.Newf(status.InvalidArgument, "micro：请求数据反序列化失败, %v", err)
 	}
 
-	in := []reflect.Value{reflect.ValueOf(ctx), resPtr}
+	if method.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, method.timeout)
+		defer cancel()
+	}
 
 	// 调用服务并获得相应
-	results := method.Call(in)
+	results := method.fn.Call([]reflect.Value{reflect.ValueOf(ctx), argPtr})
 
+	var callErr error
+	if !results[1].IsNil() {
+		callErr = results[1].Interface().(error)
+	}
+
+	// oneway请求不需要响应数据，只关心方法返回的错误
 	if req.IsOneway() {
-		return nil, errs.ErrOneway
+		return nil, callErr
 	}
 
 	var data []byte
 
-	if results[0].Interface() != nil {
+	if !results[0].IsNil() {
 		data, err = serializer.Serialize(results[0].Interface())
 
 		if err != nil {
-			log.Printf("结果序列化出错了")
-			return nil, err
+			return nil, fmt.Errorf("micro：响应数据序列化失败, %w", err)
 		}
 
+		stats.respSize = len(data)
+
 		if cTyp := req.Compressor; cTyp != 0 {
 			compressor, ok := r.compressors[cTyp]
 			if !ok {
-				return nil, errors.New("找不到相应的压缩算法支持")
+				return nil, unsupportedCompressor(cTyp, r.compressors)
 			}
 			data, err = compressor.Compress(data)
 			if err != nil {
-				log.Printf("请求数据解压缩失败")
-				return nil, err
+				return nil, fmt.Errorf("micro：响应数据压缩失败, %w", err)
 			}
 		}
 	}
 
-	if results[1].Interface() != nil {
-		log.Printf("调用出错了")
-		return data, results[1].Interface().(error)
+	return data, callErr
+}
+
+func unsupportedCompressor(code uint8, compressors map[uint8]compress.Compressor) error {
+	return status.Newf(status.Unimplemented, "micro：不支持的压缩算法 %d", code).
+		WithDetails(codes(compressors)...)
+}
+
+// codes 返回所有已经注册的序列化协议或者压缩算法的编码，按照从小到大排序
+func codes[T any](m map[uint8]T) []string {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, int(k))
 	}
+	sort.Ints(keys)
 
-	return data, nil
+	res := make([]string, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, strconv.Itoa(k))
+	}
+	return res
 }
@@ -0,0 +1,232 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/logger"
+	"github.com/uzziahlin/transport/rpc/message"
+)
+
+func TestEndPoint_handlerLog(t *testing.T) {
+	buf := &syncBuffer{}
+
+	endpoint := NewEndPoint(":8081", WithServerLogger(logger.NewStdLogger(log.New(buf, "", 0), logger.LevelDebug)))
+
+	service := &UserServiceErr{}
+	service.Err = "this is the err"
+	endpoint.Register(service)
+
+	client, server := net.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		endpoint.handler(server)
+	}()
+
+	reqEncoder := &message.DefaultRequestEncoder{}
+	respEncoder := &message.DefaultResponseEncoder{}
+
+	testCases := []struct {
+		name      string
+		req       *message.Request
+		wantError string
+	}{
+		{
+			name: "service error",
+			req: &message.Request{
+				RequestHeader: message.RequestHeader{
+					Header:      message.Header{MessageId: 7, Serializer: 1},
+					ServiceName: "user-service",
+					MethodName:  "GetById",
+				},
+				Data: []byte(`{"Id":"1"}`),
+			},
+			wantError: "this is the err",
+		},
+		{
+			name: "invalid timeout",
+			req: &message.Request{
+				RequestHeader: message.RequestHeader{
+					Header:      message.Header{MessageId: 8, Serializer: 1},
+					ServiceName: "user-service",
+					MethodName:  "GetById",
+					Meta:        map[string]string{"sys_timeout": "abc"},
+				},
+				Data: []byte(`{"Id":"1"}`),
+			},
+			wantError: `micro：过期时间 "abc" 格式不对`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.Write(reqEncoder.Encode(tc.req))
+			require.NoError(t, err)
+
+			data, err := RpcReader(client)
+			require.NoError(t, err)
+
+			resp, err := respEncoder.Decode(data)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.req.MessageId, resp.MessageId)
+			assert.Equal(t, tc.wantError, resp.Error)
+		})
+	}
+
+	require.NoError(t, client.Close())
+	<-done
+
+	assert.Equal(t, `[DEBUG] 服务方法返回错误 peer=pipe service=user-service method=GetById message_id=7 error="this is the err"
+[ERROR] 请求处理失败 peer=pipe service=user-service method=GetById message_id=8 error="micro：过期时间 \"abc\" 格式不对"
+[DEBUG] 连接已关闭 peer=pipe
+`, buf.String())
+}
+
+func TestEndPoint_handlerConcurrency(t *testing.T) {
+	testCases := []struct {
+		name        string
+		concurrency int
+		// wantOrder 响应的消息id的顺序
+		wantOrder []uint32
+	}{
+		{
+			// 串行处理，慢请求阻塞了后边的请求，响应顺序与请求顺序一致
+			name:        "serial",
+			concurrency: 1,
+			wantOrder:   []uint32{1, 2},
+		},
+		{
+			// 并发处理，快请求先返回
+			name:        "concurrent",
+			concurrency: 2,
+			wantOrder:   []uint32{2, 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := NewEndPoint(":8081", WithMaxConcurrentRequestsPerConn(tc.concurrency))
+			endpoint.Register(&orderedService{release: make(chan struct{})})
+
+			client, server := net.Pipe()
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				endpoint.handler(server)
+			}()
+
+			reqEncoder := &message.DefaultRequestEncoder{}
+			respEncoder := &message.DefaultResponseEncoder{}
+
+			// 不等待响应，连续发送两个请求
+			for i, id := range []string{"slow", "fast"} {
+				_, err := client.Write(reqEncoder.Encode(&message.Request{
+					RequestHeader: message.RequestHeader{
+						Header:      message.Header{MessageId: uint32(i + 1), Serializer: 1},
+						ServiceName: "ordered-service",
+						MethodName:  "GetById",
+					},
+					Data: []byte(`{"Id":"` + id + `"}`),
+				}))
+				require.NoError(t, err)
+			}
+
+			var order []uint32
+			for range tc.wantOrder {
+				data, err := RpcReader(client)
+				require.NoError(t, err)
+
+				resp, err := respEncoder.Decode(data)
+				require.NoError(t, err)
+				assert.Empty(t, resp.Error)
+
+				order = append(order, resp.MessageId)
+			}
+
+			assert.Equal(t, tc.wantOrder, order)
+
+			require.NoError(t, client.Close())
+			<-done
+		})
+	}
+}
+
+// orderedService slow请求会等待fast请求到达，最多等待200ms
+type orderedService struct {
+	release chan struct{}
+}
+
+func (o *orderedService) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
+	if req.Id == "slow" {
+		select {
+		case <-o.release:
+		case <-time.After(200 * time.Millisecond):
+		}
+	} else {
+		close(o.release)
+	}
+	return &UserResp{Content: req.Id}, nil
+}
+
+func (o *orderedService) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "ordered-service",
+	}
+}
+
+// syncBuffer 并发安全的bytes.Buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func TestEndPoint_drainLateConn(t *testing.T) {
+	endpoint := NewEndPoint("")
+
+	// 模拟已经被Server接受，但是在优雅关闭开始之后才执行到addConn的连接
+	endpoint.drain()
+
+	server, client := net.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		endpoint.handler(server)
+	}()
+
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	// 连接收到GOAWAY之后被关闭
+	data, err := io.ReadAll(client)
+	require.NoError(t, err)
+
+	resp, err := endpoint.respEncoder.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, message.GoAwayMessageId, resp.MessageId)
+
+	<-done
+}
@@ -3,5 +3,11 @@ package errs
 import "errors"
 
 var (
-	ErrOneway = errors.New("micro: oneway error")
+	// ErrOneway oneway调用曾经在发送成功时返回的错误
+	//
+	// Deprecated: oneway调用发送成功时返回nil，不再返回该错误
+	ErrOneway        = errors.New("micro: oneway error")
+	ErrFutureNotDone = errors.New("micro: future not done")
+	ErrServerClosed  = errors.New("micro: server closed")
+	ErrClientClosed  = errors.New("micro: client closed")
 )
@@ -0,0 +1,101 @@
+package rpc
+
+import (
+	"context"
+	"github.com/uzziahlin/transport/rpc/errs"
+)
+
+// Awaitable 可等待的异步结果，WaitAll 通过它等待不同响应类型的Future
+type Awaitable interface {
+	Done() <-chan struct{}
+	Err() error
+}
+
+// Future 异步调用的结果，调用完成之后Done返回的channel会被关闭
+type Future[Resp any] struct {
+	done chan struct{}
+	res  *Resp
+	err  error
+}
+
+// Async 在新的g中执行fn，并立即返回代表其结果的Future
+// 可以用来包装InitProxy生成的同步桩函数
+func Async[Resp any](ctx context.Context, fn func(ctx context.Context) (*Resp, error)) *Future[Resp] {
+	f := &Future[Resp]{
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(f.done)
+		f.res, f.err = fn(ctx)
+	}()
+
+	return f
+}
+
+// InvokeAsync Invoke 的异步版本
+func InvokeAsync[Req any, Resp any](ctx context.Context, caller *Caller, service, method string, req *Req) *Future[Resp] {
+	return Async(ctx, func(ctx context.Context) (*Resp, error) {
+		return Invoke[Req, Resp](ctx, caller, service, method, req)
+	})
+}
+
+// CallAsync Call 的异步版本
+func (m *MethodHandle[Req, Resp]) CallAsync(ctx context.Context, req *Req) *Future[Resp] {
+	return Async(ctx, func(ctx context.Context) (*Resp, error) {
+		return m.Call(ctx, req)
+	})
+}
+
+// Done 返回一个在调用完成时关闭的channel
+func (f *Future[Resp]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait 阻塞直到调用完成或者ctx过期
+// ctx过期只代表停止等待，不会取消调用本身，调用的取消由发起调用时的ctx控制
+func (f *Future[Resp]) Wait(ctx context.Context) (*Resp, error) {
+	select {
+	case <-f.done:
+		return f.res, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Result 非阻塞地获取调用结果，调用未完成时返回errs.ErrFutureNotDone
+func (f *Future[Resp]) Result() (*Resp, error) {
+	select {
+	case <-f.done:
+		return f.res, f.err
+	default:
+		return nil, errs.ErrFutureNotDone
+	}
+}
+
+// Err 返回调用的错误，调用未完成时返回errs.ErrFutureNotDone
+func (f *Future[Resp]) Err() error {
+	_, err := f.Result()
+	return err
+}
+
+// WaitAll 等待所有的Future完成，所有Future共享ctx的过期时间
+// 如果ctx先过期，则返回ctx.Err()，否则按照传入顺序返回第一个调用错误
+// 每个调用的结果仍然需要通过各自的Future获取
+func WaitAll(ctx context.Context, futures ...Awaitable) error {
+	for _, f := range futures {
+		select {
+		case <-f.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for _, f := range futures {
+		if err := f.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/errs"
+)
+
+func TestInvokeAsync(t *testing.T) {
+	endpoint := NewEndPoint(":8081")
+
+	endpoint.Register(&UserServiceImpl{})
+
+	caller := NewProxyConstructor().NewProxyCaller(endpoint)
+
+	f1 := InvokeAsync[UserReq, UserResp](context.Background(), caller, "user-service", "GetById", &UserReq{Id: "1"})
+
+	handle := NewMethodHandle[UserReq, UserResp](caller, "user-service", "GetById")
+	f2 := handle.CallAsync(context.Background(), &UserReq{Id: "2"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, WaitAll(ctx, f1, f2))
+
+	res, err := f1.Result()
+	require.NoError(t, err)
+	assert.Equal(t, "response: 1", res.Content)
+
+	res, err = f2.Wait(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "response: 2", res.Content)
+}
+
+func TestFuture(t *testing.T) {
+	release := make(chan struct{})
+
+	slow := Async(context.Background(), func(ctx context.Context) (*UserResp, error) {
+		<-release
+		return &UserResp{Content: "slow"}, nil
+	})
+
+	failed := Async(context.Background(), func(ctx context.Context) (*UserResp, error) {
+		return nil, errors.New("this is the err")
+	})
+
+	_, err := slow.Result()
+	assert.Equal(t, errs.ErrFutureNotDone, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, WaitAll(ctx, failed, slow))
+
+	close(release)
+
+	<-slow.Done()
+
+	res, err := slow.Result()
+	require.NoError(t, err)
+	assert.Equal(t, "slow", res.Content)
+
+	assert.Equal(t, errors.New("this is the err"), WaitAll(context.Background(), slow, failed))
+}
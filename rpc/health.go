@@ -0,0 +1,260 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/status"
+	"sync"
+	"time"
+)
+
+// HealthServiceName 内置健康检查服务的服务名
+const HealthServiceName = "rpc.health"
+
+// healthWatchTimeout Watch最长的等待时间，超过之后返回当前的状态，客户端需要再次发起Watch
+const healthWatchTimeout = 30 * time.Second
+
+// HealthStatus 服务的健康状态，取值与gRPC的健康检查协议保持一致
+type HealthStatus int32
+
+const (
+	HealthUnknown HealthStatus = iota
+	HealthServing
+	HealthNotServing
+	// HealthServiceUnknown 服务没有注册，只会出现在Watch的结果中
+	HealthServiceUnknown
+)
+
+var healthStatusNames = map[HealthStatus]string{
+	HealthUnknown:        "UNKNOWN",
+	HealthServing:        "SERVING",
+	HealthNotServing:     "NOT_SERVING",
+	HealthServiceUnknown: "SERVICE_UNKNOWN",
+}
+
+func (s HealthStatus) String() string {
+	if name, ok := healthStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("HealthStatus(%d)", int32(s))
+}
+
+// MarshalText 使用json序列化协议时，健康状态编码为SERVING、NOT_SERVING等名称
+func (s HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *HealthStatus) UnmarshalText(text []byte) error {
+	for k, name := range healthStatusNames {
+		if name == string(text) {
+			*s = k
+			return nil
+		}
+	}
+	return fmt.Errorf("micro：未知的健康状态 %q", text)
+}
+
+// healthState 记录EndPoint以及每个服务的健康状态，服务名为空字符串代表整个EndPoint
+// 状态发生变化时关闭changed，唤醒所有等待状态变化的Watch
+type healthState struct {
+	mu       sync.Mutex
+	statuses map[string]HealthStatus
+	changed  chan struct{}
+	// shutdown 优雅关闭之后所有服务都是HealthNotServing，不再允许修改
+	shutdown bool
+}
+
+func newHealthState() *healthState {
+	return &healthState{
+		statuses: map[string]HealthStatus{"": HealthServing},
+		changed:  make(chan struct{}),
+	}
+}
+
+func (h *healthState) get(service string) (HealthStatus, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.statuses[service]
+	return s, ok
+}
+
+func (h *healthState) set(service string, s HealthStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.shutdown {
+		return
+	}
+
+	if cur, ok := h.statuses[service]; ok && cur == s {
+		return
+	}
+
+	h.statuses[service] = s
+	h.notifyLocked()
+}
+
+// remove 删除服务的状态，之后查询的结果为服务不存在
+func (h *healthState) remove(service string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.statuses[service]; !ok {
+		return
+	}
+
+	delete(h.statuses, service)
+	h.notifyLocked()
+}
+
+// drain 将所有服务设置为HealthNotServing，之后的修改都会被忽略
+func (h *healthState) drain() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.shutdown = true
+
+	for service := range h.statuses {
+		h.statuses[service] = HealthNotServing
+	}
+
+	h.notifyLocked()
+}
+
+func (h *healthState) isDrained() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.shutdown
+}
+
+func (h *healthState) notifyLocked() {
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// wait 等待服务的状态不再是last，ctx结束时返回当前的状态
+// 服务不存在时状态为HealthServiceUnknown
+func (h *healthState) wait(ctx context.Context, service string, last HealthStatus) HealthStatus {
+	for {
+		h.mu.Lock()
+		cur, ok := h.statuses[service]
+		if !ok {
+			cur = HealthServiceUnknown
+		}
+		changed, shutdown := h.changed, h.shutdown
+		h.mu.Unlock()
+
+		if cur != last || shutdown {
+			return cur
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return cur
+		}
+	}
+}
+
+// SetServingStatus 设置服务的健康状态，service为空字符串时设置整个EndPoint的状态
+// 注册服务时状态默认为HealthServing。优雅关闭开始之后所有服务都是HealthNotServing，修改会被忽略
+func (e *EndPoint) SetServingStatus(service string, s HealthStatus) {
+	e.health.set(service, s)
+}
+
+// ServingStatus 返回服务的健康状态，服务不存在时返回HealthServiceUnknown
+func (e *EndPoint) ServingStatus(service string) HealthStatus {
+	s, ok := e.health.get(service)
+	if !ok {
+		return HealthServiceUnknown
+	}
+	return s
+}
+
+// WithHealthCheck 注册内置的健康检查服务，客户端可以通过HealthClient查询健康状态
+func WithHealthCheck() EndPointOpt {
+	return func(e *EndPoint) {
+		e.Register(&healthService{ep: e})
+	}
+}
+
+type HealthCheckReq struct {
+	// Service 为空字符串时查询整个EndPoint的状态
+	Service string `json:"service"`
+}
+
+type HealthCheckResp struct {
+	Status HealthStatus `json:"status"`
+}
+
+type HealthWatchReq struct {
+	Service string `json:"service"`
+	// Last 客户端已知的状态，第一次Watch时为HealthUnknown
+	Last HealthStatus `json:"last"`
+}
+
+// HealthClient 健康检查服务的客户端，通过ProxyConstructor.InitProxy初始化
+type HealthClient struct {
+	// Addr 服务端地址
+	Addr string
+
+	// Check 返回服务当前的状态，服务不存在时返回status.NotFound
+	Check func(ctx context.Context, req *HealthCheckReq) (*HealthCheckResp, error) `rpc:"idempotent,serializer=json"`
+
+	// Watch 状态与req.Last不同时立即返回，否则等待状态发生变化，最长等待30秒
+	Watch func(ctx context.Context, req *HealthWatchReq) (*HealthCheckResp, error) `rpc:"idempotent,serializer=json"`
+}
+
+func (c *HealthClient) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: HealthServiceName,
+		Addr:        c.Addr,
+	}
+}
+
+// WatchHealth 持续监听服务的健康状态，每次状态发生变化时调用fn，第一次调用fn时为当前的状态
+// 直到ctx结束或者调用失败才返回
+func WatchHealth(ctx context.Context, c *HealthClient, service string, fn func(HealthStatus)) error {
+	last := HealthUnknown
+	for {
+		resp, err := c.Watch(ctx, &HealthWatchReq{Service: service, Last: last})
+		if err != nil {
+			return err
+		}
+		if resp.Status != last {
+			last = resp.Status
+			fn(last)
+		}
+	}
+}
+
+// isHealthWatch 请求是否为健康检查服务的Watch请求
+func isHealthWatch(req *message.Request) bool {
+	return req.ServiceName == HealthServiceName && req.MethodName == "Watch"
+}
+
+type healthService struct {
+	ep *EndPoint
+}
+
+func (s *healthService) Check(ctx context.Context, req *HealthCheckReq) (*HealthCheckResp, error) {
+	st, ok := s.ep.health.get(req.Service)
+	if !ok {
+		return nil, status.Newf(status.NotFound, "micro：服务 %q 不存在", req.Service)
+	}
+	return &HealthCheckResp{Status: st}, nil
+}
+
+func (s *healthService) Watch(ctx context.Context, req *HealthWatchReq) (*HealthCheckResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthWatchTimeout)
+	defer cancel()
+
+	return &HealthCheckResp{Status: s.ep.health.wait(ctx, req.Service, req.Last)}, nil
+}
+
+func (s *healthService) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: HealthServiceName,
+	}
+}
@@ -0,0 +1,250 @@
+package rpc
+
+import (
+	"context"
+	"github.com/uzziahlin/transport/logger"
+	"github.com/uzziahlin/transport/rpc/errs"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithHTTPHealthProbe 在addr上开启HTTP健康检查，供无法使用rpc协议的负载均衡或者编排系统使用
+// 响应与HealthHandler相同，随EndPoint一起启动以及关闭
+func WithHTTPHealthProbe(addr string) EndPointOpt {
+	return func(e *EndPoint) {
+		e.probes = append(e.probes, &httpProbe{
+			addr: addr,
+			ep:   e,
+		})
+	}
+}
+
+// WithTCPHealthProbe 在addr上开启TCP健康检查，EndPoint的状态为HealthServing时监听addr，
+// 否则关闭监听，探测方通过能否建立连接判断健康状态，建立的连接会被立即关闭
+func WithTCPHealthProbe(addr string) EndPointOpt {
+	return func(e *EndPoint) {
+		e.probes = append(e.probes, &tcpProbe{
+			addr: addr,
+			ep:   e,
+		})
+	}
+}
+
+// HealthHandler 返回HTTP健康检查的handler，通过查询参数service指定服务，默认为整个EndPoint
+// HealthServing返回200，HealthNotServing返回503，服务不存在返回404，响应体为状态名
+func (e *EndPoint) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := e.ServingStatus(r.URL.Query().Get("service"))
+
+		code := http.StatusServiceUnavailable
+		switch s {
+		case HealthServing:
+			code = http.StatusOK
+		case HealthServiceUnknown:
+			code = http.StatusNotFound
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte(s.String()))
+	})
+}
+
+// healthProbe 健康检查的探针，start在EndPoint开始处理请求前调用，监听失败时EndPoint不会启动
+type healthProbe interface {
+	start() error
+	close() error
+}
+
+// startProbes 启动所有探针，多次调用只会启动一次
+func (e *EndPoint) startProbes() error {
+	e.probesOnce.Do(func() {
+		for i, p := range e.probes {
+			if err := p.start(); err != nil {
+				for _, started := range e.probes[:i] {
+					_ = started.close()
+				}
+				e.probesErr = err
+				return
+			}
+		}
+	})
+	return e.probesErr
+}
+
+// closeProbes 关闭所有探针，尚未启动时阻止之后再启动，正在启动时等待启动完成
+func (e *EndPoint) closeProbes() {
+	e.probesOnce.Do(func() {
+		e.probesErr = errs.ErrServerClosed
+	})
+
+	if e.probesErr != nil {
+		return
+	}
+
+	for _, p := range e.probes {
+		_ = p.close()
+	}
+}
+
+type httpProbe struct {
+	addr   string
+	ep     *EndPoint
+	server *http.Server
+}
+
+func (p *httpProbe) start() error {
+	l, err := net.Listen("tcp", p.addr)
+	if err != nil {
+		return err
+	}
+
+	p.server = &http.Server{Handler: p.ep.HealthHandler()}
+
+	go func() {
+		_ = p.server.Serve(l)
+	}()
+
+	return nil
+}
+
+func (p *httpProbe) close() error {
+	if p.server == nil {
+		return nil
+	}
+	return p.server.Close()
+}
+
+type tcpProbe struct {
+	addr string
+	ep   *EndPoint
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+func (p *tcpProbe) start() error {
+	last, _ := p.ep.health.get("")
+
+	// 第一次监听在启动时完成，地址不可用时EndPoint不会启动
+	if last == HealthServing {
+		if err := p.listen(); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	go p.run(ctx, last)
+
+	return nil
+}
+
+// run 跟随EndPoint的健康状态开启或者关闭监听，优雅关闭开始之后不会再监听
+func (p *tcpProbe) run(ctx context.Context, last HealthStatus) {
+	defer close(p.done)
+
+	for {
+		cur := p.ep.health.wait(ctx, "", last)
+		if ctx.Err() != nil {
+			return
+		}
+		last = cur
+
+		if cur != HealthServing {
+			p.closeListener()
+			if p.ep.health.isDrained() {
+				return
+			}
+			continue
+		}
+
+		last = p.listenWithRetry(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+const (
+	tcpProbeRetryMin = 100 * time.Millisecond
+	tcpProbeRetryMax = 5 * time.Second
+)
+
+// listenWithRetry 监听失败时按照指数退避重试，直到监听成功、状态不再是HealthServing或者ctx结束，
+// 避免EndPoint处于HealthServing时探针却一直没有监听。返回最后观察到的状态
+func (p *tcpProbe) listenWithRetry(ctx context.Context) HealthStatus {
+	backoff := tcpProbeRetryMin
+
+	for {
+		err := p.listen()
+		if err == nil {
+			return HealthServing
+		}
+
+		p.ep.logger.Error("健康检查监听失败，稍后重试", logger.KeyError, err)
+
+		waitCtx, cancel := context.WithTimeout(ctx, backoff)
+		cur := p.ep.health.wait(waitCtx, "", HealthServing)
+		cancel()
+
+		if ctx.Err() != nil || cur != HealthServing {
+			return cur
+		}
+
+		backoff *= 2
+		if backoff > tcpProbeRetryMax {
+			backoff = tcpProbeRetryMax
+		}
+	}
+}
+
+func (p *tcpProbe) listen() error {
+	l, err := net.Listen("tcp", p.addr)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.listener = l
+	p.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	return nil
+}
+
+func (p *tcpProbe) closeListener() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.listener != nil {
+		_ = p.listener.Close()
+		p.listener = nil
+	}
+}
+
+func (p *tcpProbe) close() error {
+	if p.cancel == nil {
+		return nil
+	}
+	p.cancel()
+	<-p.done
+	p.closeListener()
+	return nil
+}
@@ -0,0 +1,384 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/uzziahlin/transport/rpc"
+	"github.com/uzziahlin/transport/rpc/rpctest"
+	"github.com/uzziahlin/transport/rpc/serialize/proto"
+	"github.com/uzziahlin/transport/rpc/status"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth_Check(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("", rpc.WithHealthCheck())
+	endpoint.Register(&rpc.UserServiceImpl{})
+	endpoint.SetServingStatus("order-service", rpc.HealthNotServing)
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	client := &rpc.HealthClient{}
+	require.NoError(t, server.NewConstructor().InitProxy(client))
+
+	testCases := []struct {
+		name     string
+		service  string
+		want     rpc.HealthStatus
+		wantCode status.Code
+	}{
+		{
+			name: "endpoint",
+			want: rpc.HealthServing,
+		},
+		{
+			name:    "registered service",
+			service: "user-service",
+			want:    rpc.HealthServing,
+		},
+		{
+			name:    "not serving",
+			service: "order-service",
+			want:    rpc.HealthNotServing,
+		},
+		{
+			name:     "unknown service",
+			service:  "pay-service",
+			wantCode: status.NotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.Check(context.Background(), &rpc.HealthCheckReq{Service: tc.service})
+			if tc.wantCode != status.OK {
+				assert.Equal(t, tc.wantCode, status.CodeOf(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, resp.Status)
+		})
+	}
+}
+
+func TestHealth_Watch(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("", rpc.WithHealthCheck())
+	endpoint.Register(&rpc.UserServiceImpl{})
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	client := &rpc.HealthClient{}
+	require.NoError(t, server.NewConstructor().InitProxy(client))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes := make(chan rpc.HealthStatus, 4)
+	go func() {
+		_ = rpc.WatchHealth(ctx, client, "user-service", func(s rpc.HealthStatus) {
+			changes <- s
+		})
+	}()
+
+	assert.Equal(t, rpc.HealthServing, <-changes)
+
+	endpoint.SetServingStatus("user-service", rpc.HealthNotServing)
+	assert.Equal(t, rpc.HealthNotServing, <-changes)
+
+	endpoint.SetServingStatus("user-service", rpc.HealthServing)
+	assert.Equal(t, rpc.HealthServing, <-changes)
+
+	// 优雅关闭开始之后所有服务都不再提供服务
+	go func() {
+		_ = endpoint.Shutdown(ctx)
+	}()
+	assert.Equal(t, rpc.HealthNotServing, <-changes)
+
+	endpoint.SetServingStatus("user-service", rpc.HealthServing)
+	assert.Equal(t, rpc.HealthNotServing, endpoint.ServingStatus("user-service"))
+}
+
+func TestHealth_WatchLimit(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("", rpc.WithHealthCheck(),
+		rpc.WithMaxConcurrentRequests(1), rpc.WithMaxConcurrentRequestsPerConn(1), rpc.WithMaxHealthWatchers(1))
+	endpoint.Register(&rpc.UserServiceImpl{})
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	constructor := server.NewConstructor()
+
+	client := &rpc.HealthClient{}
+	require.NoError(t, constructor.InitProxy(client))
+
+	userService := &rpc.UserService{}
+	require.NoError(t, constructor.InitProxy(userService))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 一直占用唯一的Watch名额
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			_, _ = client.Watch(ctx, &rpc.HealthWatchReq{Last: rpc.HealthServing})
+		}
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Eventually(t, func() bool {
+		watchCtx, watchCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer watchCancel()
+		_, err := client.Watch(watchCtx, &rpc.HealthWatchReq{Last: rpc.HealthServing})
+		return status.CodeOf(err) == status.ResourceExhausted
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// 等待中的Watch请求不占用业务请求的名额
+	for i := 0; i < 3; i++ {
+		resp, err := userService.GetById(ctx, &rpc.UserReq{Id: "1"})
+		require.NoError(t, err)
+		assert.Equal(t, "response: 1", resp.Content)
+	}
+
+	resp, err := client.Check(ctx, &rpc.HealthCheckReq{})
+	require.NoError(t, err)
+	assert.Equal(t, rpc.HealthServing, resp.Status)
+}
+
+func TestHealthStatus_JSON(t *testing.T) {
+	data, err := json.Marshal(&rpc.HealthCheckResp{Status: rpc.HealthNotServing})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":"NOT_SERVING"}`, string(data))
+
+	var resp rpc.HealthCheckResp
+	require.NoError(t, json.Unmarshal(data, &resp))
+	assert.Equal(t, rpc.HealthNotServing, resp.Status)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"status":"DOWN"}`), &resp))
+}
+
+func TestEndPoint_HealthHandler(t *testing.T) {
+	endpoint := rpc.NewEndPoint("")
+	endpoint.Register(&rpc.UserServiceImpl{})
+	endpoint.SetServingStatus("order-service", rpc.HealthNotServing)
+
+	testCases := []struct {
+		name     string
+		target   string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "endpoint",
+			target:   "/healthz",
+			wantCode: http.StatusOK,
+			wantBody: "SERVING",
+		},
+		{
+			name:     "serving",
+			target:   "/healthz?service=user-service",
+			wantCode: http.StatusOK,
+			wantBody: "SERVING",
+		},
+		{
+			name:     "not serving",
+			target:   "/healthz?service=order-service",
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: "NOT_SERVING",
+		},
+		{
+			name:     "unknown service",
+			target:   "/healthz?service=pay-service",
+			wantCode: http.StatusNotFound,
+			wantBody: "SERVICE_UNKNOWN",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			endpoint.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+		})
+	}
+}
+
+func TestHealthProbe(t *testing.T) {
+	t.Parallel()
+
+	httpAddr, tcpAddr := freeAddr(t), freeAddr(t)
+
+	endpoint := rpc.NewEndPoint("", rpc.WithHTTPHealthProbe(httpAddr), rpc.WithTCPHealthProbe(tcpAddr))
+	rpctest.NewInMemoryServer(t, endpoint)
+
+	httpStatus := func() int {
+		resp, err := http.Get("http://" + httpAddr)
+		if err != nil {
+			return 0
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tcpReachable := func() bool {
+		conn, err := net.DialTimeout("tcp", tcpAddr, time.Second)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}
+
+	require.Eventually(t, func() bool {
+		return httpStatus() == http.StatusOK && tcpReachable()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	endpoint.SetServingStatus("", rpc.HealthNotServing)
+	assert.Eventually(t, func() bool {
+		return httpStatus() == http.StatusServiceUnavailable && !tcpReachable()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	endpoint.SetServingStatus("", rpc.HealthServing)
+	assert.Eventually(t, tcpReachable, 5*time.Second, 10*time.Millisecond)
+
+	// 关闭EndPoint时同时关闭探针
+	require.NoError(t, endpoint.Close())
+	assert.Equal(t, 0, httpStatus())
+	assert.False(t, tcpReachable())
+}
+
+func TestHealthProbe_TCPRetry(t *testing.T) {
+	t.Parallel()
+
+	tcpAddr := freeAddr(t)
+
+	endpoint := rpc.NewEndPoint("", rpc.WithTCPHealthProbe(tcpAddr))
+	rpctest.NewInMemoryServer(t, endpoint)
+
+	tcpReachable := func() bool {
+		conn, err := net.DialTimeout("tcp", tcpAddr, time.Second)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}
+
+	require.Eventually(t, tcpReachable, 5*time.Second, 10*time.Millisecond)
+
+	endpoint.SetServingStatus("", rpc.HealthNotServing)
+	require.Eventually(t, func() bool {
+		return !tcpReachable()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// 地址被占用时恢复为HealthServing，探针监听失败
+	occupied, err := net.Listen("tcp", tcpAddr)
+	require.NoError(t, err)
+
+	endpoint.SetServingStatus("", rpc.HealthServing)
+	time.Sleep(200 * time.Millisecond)
+
+	// 地址释放之后探针重试监听成功
+	require.NoError(t, occupied.Close())
+	require.Eventually(t, tcpReachable, 5*time.Second, 10*time.Millisecond)
+
+	endpoint.SetServingStatus("", rpc.HealthNotServing)
+	assert.Eventually(t, func() bool {
+		return !tcpReachable()
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestHealthProbe_StartupFailed(t *testing.T) {
+	t.Parallel()
+
+	httpAddr, tcpAddr := freeAddr(t), freeAddr(t)
+
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = occupied.Close()
+	}()
+
+	// EndPoint的地址被占用时不会启动探针
+	endpoint := rpc.NewEndPoint(occupied.Addr().String(),
+		rpc.WithHTTPHealthProbe(httpAddr), rpc.WithTCPHealthProbe(tcpAddr))
+	require.Error(t, endpoint.Startup())
+
+	for _, addr := range []string{httpAddr, tcpAddr} {
+		l, err := net.Listen("tcp", addr)
+		require.NoError(t, err)
+		require.NoError(t, l.Close())
+	}
+
+	// listener出错退出时释放已经启动的探针
+	endpoint = rpc.NewEndPoint("", rpc.WithHTTPHealthProbe(httpAddr), rpc.WithTCPHealthProbe(tcpAddr))
+	require.Error(t, endpoint.Serve(&failingListener{Listener: occupied}))
+
+	for _, addr := range []string{httpAddr, tcpAddr} {
+		l, err := net.Listen("tcp", addr)
+		require.NoError(t, err)
+		require.NoError(t, l.Close())
+	}
+}
+
+// failingListener Accept总是返回错误
+type failingListener struct {
+	net.Listener
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	return nil, errors.New("accept failed")
+}
+
+func TestHealth_ProtoSerializer(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("", rpc.WithHealthCheck())
+	endpoint.Register(&rpc.UserServiceImpl{})
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	// 健康检查服务固定使用json，不受代理构造器默认序列化协议的影响
+	client := &rpc.HealthClient{}
+	require.NoError(t, server.NewConstructor(rpc.WithSerializer(&proto.Serializer{})).InitProxy(client))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := client.Check(ctx, &rpc.HealthCheckReq{Service: "user-service"})
+	require.NoError(t, err)
+	assert.Equal(t, rpc.HealthServing, resp.Status)
+
+	resp, err = client.Watch(ctx, &rpc.HealthWatchReq{Service: "user-service"})
+	require.NoError(t, err)
+	assert.Equal(t, rpc.HealthServing, resp.Status)
+}
+
+// freeAddr 返回一个当前可用的本地地址
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = l.Close()
+	}()
+	return l.Addr().String()
+}
@@ -0,0 +1,99 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/uzziahlin/transport/rpc/semver"
+)
+
+// Caller 泛型调用API的入口，绑定了远端代理以及代理构造器中的序列化、压缩等配置
+// Caller 与InitProxy生成的桩函数共享同一套调用流程，两种调用风格可以混合使用
+type Caller struct {
+	constructor *ProxyConstructor
+	proxy       Proxy
+}
+
+// NewCaller 创建一个请求addr的Caller
+func (p *ProxyConstructor) NewCaller(addr string) *Caller {
+	return p.NewProxyCaller(p.newRemoteProxy(addr))
+}
+
+// NewProxyCaller 使用指定的代理创建Caller，方便在测试中替换为自定义的Proxy实现
+func (p *ProxyConstructor) NewProxyCaller(proxy Proxy) *Caller {
+	return &Caller{
+		constructor: p,
+		proxy:       proxy,
+	}
+}
+
+// Invoke 以类型安全的方式发起一次远程调用，不需要借助反射构造函数，调用失败时响应为nil
+func Invoke[Req any, Resp any](ctx context.Context, caller *Caller, service, method string, req *Req) (*Resp, error) {
+	res := new(Resp)
+
+	if err := caller.constructor.call(ctx, caller.proxy, service, &methodOptions{name: method}, req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// MethodHandle 代表某个服务的一个方法，创建之后可以被重复调用
+type MethodHandle[Req any, Resp any] struct {
+	caller  *Caller
+	service string
+	opts    *methodOptions
+}
+
+// NewMethodHandle 创建service的method方法句柄，不指定分组以及版本范围，调用选项使用默认值
+func NewMethodHandle[Req any, Resp any](caller *Caller, service, method string) *MethodHandle[Req, Resp] {
+	return &MethodHandle[Req, Resp]{
+		caller:  caller,
+		service: service,
+		opts:    &methodOptions{name: method},
+	}
+}
+
+// NewServiceMethodHandle 创建info描述的服务的方法句柄，分组以及版本范围来自info，
+// tag与InitProxy中函数属性的rpc tag格式相同。调用选项在创建时解析，之后的调用不需要反射
+func NewServiceMethodHandle[Req any, Resp any](caller *Caller, info ServiceInfo, method, tag string) (*MethodHandle[Req, Resp], error) {
+	opts, reason := caller.constructor.parseMethodTag(method, tag)
+	if reason != "" {
+		return nil, fmt.Errorf("micro：服务 %s 的方法 %s 不合法, %s", info.ServiceName, method, reason)
+	}
+
+	versionRange, err := semver.ParseRange(info.Version)
+	if err != nil {
+		return nil, fmt.Errorf("micro：服务 %s 的版本范围不合法, %w", info.ServiceName, err)
+	}
+
+	opts.group = info.Group
+	opts.version = info.Version
+	opts.versionRange = versionRange
+
+	return &MethodHandle[Req, Resp]{
+		caller:  caller,
+		service: info.ServiceName,
+		opts:    opts,
+	}, nil
+}
+
+// Call 调用方法并返回响应，调用失败时响应为nil
+func (m *MethodHandle[Req, Resp]) Call(ctx context.Context, req *Req) (*Resp, error) {
+	res := new(Resp)
+
+	if err := m.caller.constructor.call(ctx, m.caller.proxy, m.service, m.opts, req, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Service 返回方法所属的服务名
+func (m *MethodHandle[Req, Resp]) Service() string {
+	return m.service
+}
+
+// Method 返回方法名
+func (m *MethodHandle[Req, Resp]) Method() string {
+	return m.opts.name
+}
@@ -0,0 +1,177 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/compress"
+	"github.com/uzziahlin/transport/rpc/proto/user_service/gen"
+	"github.com/uzziahlin/transport/rpc/serialize/proto"
+)
+
+func TestInvoke(t *testing.T) {
+	// EndPoint 本身实现了Proxy接口，直接作为代理可以避免网络请求
+	endpoint := NewEndPoint(":8081")
+
+	endpoint.Register(&UserServiceImpl{})
+
+	constructor := NewProxyConstructor()
+
+	caller := constructor.NewProxyCaller(endpoint)
+
+	testCases := []struct {
+		name    string
+		ctx     context.Context
+		req     *UserReq
+		wantRes *UserResp
+	}{
+		{
+			name:    "normal",
+			ctx:     context.Background(),
+			req:     &UserReq{Id: "this is the user id"},
+			wantRes: &UserResp{Content: "response: this is the user id"},
+		},
+		{
+			name:    "with compressor",
+			ctx:     compress.Context(context.Background(), compress.GZIP),
+			req:     &UserReq{Id: "compressed"},
+			wantRes: &UserResp{Content: "response: compressed"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := Invoke[UserReq, UserResp](tc.ctx, caller, "user-service", "GetById", tc.req)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestMethodHandle_Call(t *testing.T) {
+	endpoint := NewEndPoint(":8081")
+
+	service := &UserServiceProto{}
+
+	service.Msg = "this is the msg"
+	service.Err = "this is the err"
+
+	endpoint.Register(service)
+
+	constructor := NewProxyConstructor(WithSerializer(&proto.Serializer{}))
+
+	handle := NewMethodHandle[gen.UserReq, gen.UserResp](constructor.NewProxyCaller(endpoint), "user-service", "GetByIdProto")
+
+	res, err := handle.Call(context.Background(), &gen.UserReq{Id: "this is the user id"})
+
+	assert.Equal(t, errors.New("this is the err"), err)
+	// 调用失败时不返回响应
+	assert.Nil(t, res)
+}
+
+// TestNewCaller_SharedProxy 同一个地址的Caller共享代理以及连接池，不会每次都建立新的连接
+func TestNewCaller_SharedProxy(t *testing.T) {
+	endpoint := NewEndPoint("")
+	require.NoError(t, endpoint.Register(&UserServiceImpl{}))
+
+	var dials int32
+	constructor := NewProxyConstructor(WithDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		client, server := net.Pipe()
+		go endpoint.handler(server)
+		return client, nil
+	}))
+
+	for i := 0; i < 10; i++ {
+		caller := constructor.NewCaller("user-service:8080")
+		resp, err := Invoke[UserReq, UserResp](context.Background(), caller, "user-service", "GetById", &UserReq{Id: "1"})
+		require.NoError(t, err)
+		assert.Equal(t, "response: 1", resp.Content)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dials))
+	assert.Len(t, constructor.proxies, 1)
+
+	constructor.NewCaller("other-service:8080")
+	assert.Len(t, constructor.proxies, 2)
+
+	require.NoError(t, constructor.Close())
+	assert.Empty(t, constructor.proxies)
+}
+
+func TestInvoke_InteropWithProxy(t *testing.T) {
+	endpoint := NewEndPoint(":8081")
+
+	endpoint.Register(&UserServiceImpl{})
+
+	constructor := NewProxyConstructor()
+
+	// 桩函数和泛型调用使用同一个代理
+	userService := &UserService{}
+
+	err := constructor.setFuncField(userService, endpoint)
+	require.NoError(t, err)
+
+	stubRes, err := userService.GetById(context.Background(), &UserReq{Id: "interop"})
+	require.NoError(t, err)
+
+	res, err := Invoke[UserReq, UserResp](context.Background(), constructor.NewProxyCaller(endpoint), "user-service", "GetById", &UserReq{Id: "interop"})
+	require.NoError(t, err)
+
+	assert.Equal(t, stubRes, res)
+}
+
+func TestNewServiceMethodHandle(t *testing.T) {
+	endpoint := NewEndPoint(":8081")
+
+	endpoint.Register(&UserServiceImpl{})
+
+	caller := NewProxyConstructor().NewProxyCaller(endpoint)
+
+	testCases := []struct {
+		name    string
+		info    ServiceInfo
+		tag     string
+		wantErr string
+	}{
+		{
+			name: "normal",
+			info: ServiceInfo{ServiceName: "user-service"},
+			tag:  "timeout=1s,idempotent,retry=1",
+		},
+		{
+			name:    "invalid tag",
+			info:    ServiceInfo{ServiceName: "user-service"},
+			tag:     "retry=1",
+			wantErr: "micro：服务 user-service 的方法 GetById 不合法, rpc tag 只有声明了idempotent的方法才能重试",
+		},
+		{
+			name:    "invalid version",
+			info:    ServiceInfo{ServiceName: "user-service", Version: "abc"},
+			wantErr: "micro：服务 user-service 的版本范围不合法",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handle, err := NewServiceMethodHandle[UserReq, UserResp](caller, tc.info, "GetById", tc.tag)
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "user-service", handle.Service())
+			assert.Equal(t, "GetById", handle.Method())
+
+			res, err := handle.Call(context.Background(), &UserReq{Id: "handle"})
+			require.NoError(t, err)
+			assert.Equal(t, &UserResp{Content: "response: handle"}, res)
+		})
+	}
+}
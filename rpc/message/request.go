@@ -1,13 +1,6 @@
 package message
 
-import (
-	"strings"
-)
-
-const (
-	itemSplitter = '\n'
-	kvSplitter   = '\r'
-)
+import "fmt"
 
 type reqMessage struct {
 	message
@@ -24,13 +17,7 @@ func (m *reqMessage) setHeader(header *RequestHeader) {
 
 	m.putByte(itemSplitter)
 
-	for k, v := range header.Meta {
-		m.putString(k)
-		// kv中间写入分隔符，方便后边解析报文
-		m.putByte(kvSplitter)
-		m.putString(v)
-		m.putByte(itemSplitter)
-	}
+	m.putMeta(header.Meta)
 }
 
 func (m *reqMessage) setMessage(req *Request) {
@@ -38,43 +25,40 @@ func (m *reqMessage) setMessage(req *Request) {
 	m.setBody(req.Data)
 }
 
-func (m *reqMessage) getHeader() *RequestHeader {
+func (m *reqMessage) getHeader() (*RequestHeader, error) {
+	if len(m.data) < fixedHeadLen {
+		return nil, fmt.Errorf("micro：请求长度 %d 小于头部的最小长度", len(m.data))
+	}
 
 	reqHeader := &RequestHeader{
 		Header: *(m.message.getHeader()),
 	}
 
+	// 请求的格式没有变化，兼容没有设置版本号的客户端
+	if v := reqHeader.Version; v > ProtocolVersion {
+		return nil, fmt.Errorf("micro：不支持的请求协议版本 %d", v)
+	}
+
+	if err := reqHeader.checkLen(len(m.data), fixedHeadLen); err != nil {
+		return nil, err
+	}
+
 	headLen := int(reqHeader.HeaderLen)
 
 	reqHeader.ServiceName = string(m.readToByte(itemSplitter, headLen))
 
 	reqHeader.MethodName = string(m.readToByte(itemSplitter, headLen))
 
-	mts := m.readToIndex(headLen)
-
-	if mts != nil && len(mts) > 0 {
-		m := make(map[string]string, 4)
-
-		metas := string(mts)
+	reqHeader.Meta = m.getMeta(headLen)
 
-		seps := strings.Split(metas, string(itemSplitter))
-
-		for _, sep := range seps {
-			if sep == "" {
-				continue
-			}
-			kv := strings.Split(sep, string(kvSplitter))
-			m[kv[0]] = kv[1]
-		}
-
-		reqHeader.Meta = m
-	}
-
-	return reqHeader
+	return reqHeader, nil
 }
 
-func (m *reqMessage) getMessage() *Request {
-	header := m.getHeader()
+func (m *reqMessage) getMessage() (*Request, error) {
+	header, err := m.getHeader()
+	if err != nil {
+		return nil, err
+	}
 	body := m.getBody(int(header.DataLen))
 
 	if len(body) == 0 {
@@ -84,7 +68,7 @@ func (m *reqMessage) getMessage() *Request {
 	return &Request{
 		RequestHeader: *header,
 		Data:          body,
-	}
+	}, nil
 }
 
 type RequestHeader struct {
@@ -99,13 +83,7 @@ func (r *RequestHeader) calHeadLen() {
 		len(r.ServiceName) + 1 +
 		len(r.MethodName) + 1
 
-	for k, v := range r.Meta {
-		// +1是因为k后边跟着kv分隔符
-		res += len(k) + 1
-
-		// +1是因为v后边跟着\n分隔符
-		res += len(v) + 1
-	}
+	res += metaLen(r.Meta)
 
 	r.HeaderLen = uint32(res)
 }
@@ -134,6 +112,8 @@ type DefaultRequestEncoder struct {
 
 func (d *DefaultRequestEncoder) Encode(req *Request) []byte {
 
+	req.Version = ProtocolVersion
+
 	// 是否在这里计算协议头长度和协议体长度
 	req.calHeadLen()
 	req.calDataLen()
@@ -159,5 +139,5 @@ func (d *DefaultRequestEncoder) Decode(data []byte) (*Request, error) {
 		},
 	}
 
-	return reqMsg.getMessage(), nil
+	return reqMsg.getMessage()
 }
@@ -21,7 +21,7 @@ func TestRequestEncoder_EncodeDecode(t *testing.T) {
 					RequestHeader: RequestHeader{
 						Header: Header{
 							MessageId:  1234,
-							Version:    3,
+							Version:    ProtocolVersion,
 							Compressor: 2,
 							Serializer: 3,
 						},
@@ -41,7 +41,7 @@ func TestRequestEncoder_EncodeDecode(t *testing.T) {
 					RequestHeader: RequestHeader{
 						Header: Header{
 							MessageId:  1234,
-							Version:    3,
+							Version:    ProtocolVersion,
 							Compressor: 2,
 							Serializer: 3,
 						},
@@ -65,7 +65,7 @@ func TestRequestEncoder_EncodeDecode(t *testing.T) {
 					RequestHeader: RequestHeader{
 						Header: Header{
 							MessageId:  1234,
-							Version:    3,
+							Version:    ProtocolVersion,
 							Compressor: 2,
 							Serializer: 3,
 						},
@@ -86,7 +86,7 @@ func TestRequestEncoder_EncodeDecode(t *testing.T) {
 					RequestHeader: RequestHeader{
 						Header: Header{
 							MessageId:  1234,
-							Version:    3,
+							Version:    ProtocolVersion,
 							Compressor: 2,
 							Serializer: 3,
 						},
@@ -114,3 +114,55 @@ func TestRequestEncoder_EncodeDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestEncoder_DecodeVersion(t *testing.T) {
+	encode := func(version uint8) []byte {
+		data := (&DefaultRequestEncoder{}).Encode(&Request{
+			RequestHeader: RequestHeader{
+				Header:      Header{MessageId: 1},
+				ServiceName: "user-service",
+				MethodName:  "GetById",
+			},
+		})
+		data[12] = version
+		return data
+	}
+
+	testCases := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{
+			name: "current",
+			data: encode(ProtocolVersion),
+		},
+		{
+			// 请求的格式没有变化，兼容没有设置版本号的客户端
+			name: "version 0",
+			data: encode(0),
+		},
+		{
+			name:    "unknown version",
+			data:    encode(ProtocolVersion + 1),
+			wantErr: true,
+		},
+		{
+			name:    "truncated",
+			data:    encode(ProtocolVersion)[:20],
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := (&DefaultRequestEncoder{}).Decode(tc.data)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "GetById", req.MethodName)
+		})
+	}
+}
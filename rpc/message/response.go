@@ -1,5 +1,10 @@
 package message
 
+import "fmt"
+
+// respMinHeadLen 响应头部的最小长度，固定部分之后是错误信息的长度
+const respMinHeadLen = fixedHeadLen + 4
+
 type respMessage struct {
 	message
 }
@@ -7,7 +12,10 @@ type respMessage struct {
 func (m *respMessage) setHeader(header *ResponseHeader) {
 	m.message.setHeader(&header.Header)
 
+	// 错误信息可能包含任意字符，所以先写入长度，再写入元数据
+	m.putUint32(uint32(len(header.Error)))
 	m.putString(header.Error)
+	m.putMeta(header.Meta)
 }
 
 func (m *respMessage) setMessage(resp *Response) {
@@ -15,25 +23,41 @@ func (m *respMessage) setMessage(resp *Response) {
 	m.setBody(resp.Data)
 }
 
-func (m *respMessage) getHeader() *ResponseHeader {
+func (m *respMessage) getHeader() (*ResponseHeader, error) {
+	if len(m.data) < respMinHeadLen {
+		return nil, fmt.Errorf("micro：响应长度 %d 小于头部的最小长度", len(m.data))
+	}
 
 	respHeader := &ResponseHeader{
 		Header: *(m.message.getHeader()),
 	}
 
-	headLen := int(respHeader.HeaderLen)
+	// 不同版本的响应头部格式不同，无法按照当前格式解析
+	if v := respHeader.Version; v != ProtocolVersion {
+		return nil, fmt.Errorf("micro：不支持的响应协议版本 %d", v)
+	}
 
-	mts := m.readToIndex(headLen)
+	if err := respHeader.checkLen(len(m.data), respMinHeadLen); err != nil {
+		return nil, err
+	}
 
-	if mts != nil {
-		respHeader.Error = string(mts)
+	headLen := int(respHeader.HeaderLen)
+
+	errLen := int(m.uint32())
+	if errLen > headLen-m.offset {
+		return nil, fmt.Errorf("micro：错误信息长度 %d 超出了响应头部", errLen)
 	}
+	respHeader.Error = string(m.bytes(errLen))
+	respHeader.Meta = m.getMeta(headLen)
 
-	return respHeader
+	return respHeader, nil
 }
 
-func (m *respMessage) getMessage() *Response {
-	header := m.getHeader()
+func (m *respMessage) getMessage() (*Response, error) {
+	header, err := m.getHeader()
+	if err != nil {
+		return nil, err
+	}
 	body := m.getBody(int(header.DataLen))
 
 	if len(body) == 0 {
@@ -43,16 +67,19 @@ func (m *respMessage) getMessage() *Response {
 	return &Response{
 		ResponseHeader: *header,
 		Data:           body,
-	}
+	}, nil
 }
 
 type ResponseHeader struct {
 	Header
 	Error string
+	// Meta 响应的元数据，例如错误码
+	Meta map[string]string
 }
 
 func (r *ResponseHeader) calHeadLen() {
-	r.HeaderLen = uint32(r.fixedHeadLen() + len(r.Error))
+	// +4是错误信息的长度
+	r.HeaderLen = uint32(r.fixedHeadLen() + 4 + len(r.Error) + metaLen(r.Meta))
 }
 
 type Response struct {
@@ -74,6 +101,8 @@ type DefaultResponseEncoder struct {
 
 func (d *DefaultResponseEncoder) Encode(resp *Response) []byte {
 
+	resp.Version = ProtocolVersion
+
 	// 先计算协议头和协议体的长度
 	resp.calHeadLen()
 	resp.calDataLen()
@@ -99,5 +128,5 @@ func (d *DefaultResponseEncoder) Decode(data []byte) (*Response, error) {
 		},
 	}
 
-	return respMsg.getMessage(), nil
+	return respMsg.getMessage()
 }
@@ -1,6 +1,7 @@
 package message
 
 import (
+	"encoding/binary"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -21,7 +22,7 @@ func TestResponseEncoder_EncodeDecode(t *testing.T) {
 					ResponseHeader: ResponseHeader{
 						Header: Header{
 							MessageId:  1234,
-							Version:    3,
+							Version:    ProtocolVersion,
 							Compressor: 2,
 							Serializer: 3,
 						},
@@ -38,7 +39,7 @@ func TestResponseEncoder_EncodeDecode(t *testing.T) {
 					ResponseHeader: ResponseHeader{
 						Header: Header{
 							MessageId:  1234,
-							Version:    3,
+							Version:    ProtocolVersion,
 							Compressor: 2,
 							Serializer: 3,
 						},
@@ -56,7 +57,7 @@ func TestResponseEncoder_EncodeDecode(t *testing.T) {
 					ResponseHeader: ResponseHeader{
 						Header: Header{
 							MessageId:  1234,
-							Version:    3,
+							Version:    ProtocolVersion,
 							Compressor: 2,
 							Serializer: 3,
 						},
@@ -74,7 +75,7 @@ func TestResponseEncoder_EncodeDecode(t *testing.T) {
 					ResponseHeader: ResponseHeader{
 						Header: Header{
 							MessageId:  1234,
-							Version:    3,
+							Version:    ProtocolVersion,
 							Compressor: 2,
 							Serializer: 3,
 						},
@@ -85,6 +86,30 @@ func TestResponseEncoder_EncodeDecode(t *testing.T) {
 				return resp
 			}(),
 		},
+		{
+			name:    "default with meta",
+			encoder: &DefaultResponseEncoder{},
+			resp: func() Response {
+				resp := Response{
+					ResponseHeader: ResponseHeader{
+						Header: Header{
+							MessageId:  1234,
+							Version:    ProtocolVersion,
+							Compressor: 2,
+							Serializer: 3,
+						},
+						// 错误信息中的分隔符不影响元数据的解析
+						Error: "micro: 程序发生错误了\r\n",
+						Meta: map[string]string{
+							"k1": "v1",
+							"k2": "v2",
+						},
+					},
+					Data: []byte("此程序正常返回响应"),
+				}
+				return resp
+			}(),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -96,3 +121,67 @@ func TestResponseEncoder_EncodeDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestResponseEncoder_DecodeInvalid(t *testing.T) {
+	valid := func() []byte {
+		return (&DefaultResponseEncoder{}).Encode(&Response{
+			ResponseHeader: ResponseHeader{
+				Header: Header{MessageId: 1},
+				Error:  "micro: 程序发生错误了",
+			},
+			Data: []byte("data"),
+		})
+	}
+
+	testCases := []struct {
+		name string
+		data func() []byte
+	}{
+		{
+			// 没有设置版本号的服务端，错误信息之前没有长度
+			name: "version 0",
+			data: func() []byte {
+				data := valid()
+				data[12] = 0
+				return data
+			},
+		},
+		{
+			name: "unknown version",
+			data: func() []byte {
+				data := valid()
+				data[12] = ProtocolVersion + 1
+				return data
+			},
+		},
+		{
+			name: "error length overflow",
+			data: func() []byte {
+				data := valid()
+				binary.BigEndian.PutUint32(data[15:19], 1<<20)
+				return data
+			},
+		},
+		{
+			name: "truncated",
+			data: func() []byte {
+				data := valid()
+				return data[:len(data)-1]
+			},
+		},
+		{
+			name: "too short",
+			data: func() []byte {
+				return valid()[:10]
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := (&DefaultResponseEncoder{}).Decode(tc.data())
+			assert.Error(t, err)
+			assert.Nil(t, resp)
+		})
+	}
+}
@@ -3,6 +3,20 @@ package message
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// ProtocolVersion 当前的协议版本，Encode时写入报文头部
+// 版本1的响应在元数据之前写入了错误信息的长度，与没有设置版本号(版本0)的响应格式不兼容
+const ProtocolVersion uint8 = 1
+
+// fixedHeadLen 报文头部中固定长度的部分
+const fixedHeadLen = 15
+
+const (
+	itemSplitter = '\n'
+	kvSplitter   = '\r'
 )
 
 type Header struct {
@@ -15,7 +29,18 @@ type Header struct {
 }
 
 func (h *Header) fixedHeadLen() int {
-	return 15
+	return fixedHeadLen
+}
+
+// checkLen 校验头部记录的长度与报文的实际长度是否一致，minHeadLen为头部的最小长度
+func (h *Header) checkLen(size int, minHeadLen int) error {
+	if int(h.HeaderLen) < minHeadLen {
+		return fmt.Errorf("micro：报文头部长度 %d 不合法", h.HeaderLen)
+	}
+	if uint64(h.HeaderLen)+uint64(h.DataLen) > uint64(size) {
+		return fmt.Errorf("micro：报文长度 %d 小于头部记录的长度 %d", size, uint64(h.HeaderLen)+uint64(h.DataLen))
+	}
+	return nil
 }
 
 // message 对报文的抽象，提供了写入和读取报文的操作
@@ -130,6 +155,48 @@ func (m *message) setHeader(header *Header) {
 	m.putUint8(header.Serializer)
 }
 
+// putMeta 写入元数据，每个kv的格式为 k\rv\n
+func (m *message) putMeta(meta map[string]string) {
+	for k, v := range meta {
+		m.putString(k)
+		// kv中间写入分隔符，方便后边解析报文
+		m.putByte(kvSplitter)
+		m.putString(v)
+		m.putByte(itemSplitter)
+	}
+}
+
+// getMeta 读取元数据直到end，没有元数据时返回nil，格式不对的kv会被忽略
+func (m *message) getMeta(end int) map[string]string {
+	mts := m.readToIndex(end)
+
+	if len(mts) == 0 {
+		return nil
+	}
+
+	res := make(map[string]string, 4)
+
+	for _, item := range strings.Split(string(mts), string(itemSplitter)) {
+		if k, v, ok := strings.Cut(item, string(kvSplitter)); ok {
+			res[k] = v
+		}
+	}
+
+	return res
+}
+
+// metaLen 计算元数据编码之后的长度
+func metaLen(meta map[string]string) int {
+	res := 0
+
+	for k, v := range meta {
+		// +1是因为k后边跟着kv分隔符，v后边跟着\n分隔符
+		res += len(k) + 1 + len(v) + 1
+	}
+
+	return res
+}
+
 // setHeader 往报文中写入消息体信息
 func (m *message) setBody(body []byte) {
 	m.putBytes(body)
@@ -154,3 +221,12 @@ func (m *message) getHeader() *Header {
 func (m *message) getBody(size int) []byte {
 	return m.bytes(size)
 }
+
+// GoAwayMessageId GOAWAY帧的消息id，客户端的请求id从1开始，不会与之冲突
+// 服务端关闭前会向每个连接发送GOAWAY帧，通知客户端不要在该连接上发送新的请求
+const GoAwayMessageId uint32 = 0
+
+// IsGoAway 判断一个完整的响应报文是否为GOAWAY帧
+func IsGoAway(data []byte) bool {
+	return len(data) >= 12 && binary.BigEndian.Uint32(data[8:12]) == GoAwayMessageId
+}
@@ -0,0 +1,110 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ReservedPrefix 框架内部使用的元数据前缀，用户不能使用该前缀的key
+const ReservedPrefix = "sys_"
+
+// MD 请求元数据，会随着请求头一起发送给服务端
+type MD map[string]string
+
+// Pairs 使用k1, v1, k2, v2...的形式创建元数据，参数个数为奇数时panic
+func Pairs(kv ...string) MD {
+	if len(kv)%2 == 1 {
+		panic(fmt.Sprintf("metadata: Pairs 参数个数必须为偶数, 实际为 %d", len(kv)))
+	}
+
+	md := make(MD, len(kv)/2)
+
+	for i := 0; i < len(kv); i += 2 {
+		md[kv[i]] = kv[i+1]
+	}
+
+	return md
+}
+
+// Get 获取key对应的值，不存在时返回空字符串
+func (md MD) Get(key string) string {
+	return md[key]
+}
+
+// Copy 返回元数据的副本
+func (md MD) Copy() MD {
+	res := make(MD, len(md))
+	for k, v := range md {
+		res[k] = v
+	}
+	return res
+}
+
+// IsReserved key是否使用了框架保留的前缀
+func IsReserved(key string) bool {
+	return strings.HasPrefix(key, ReservedPrefix)
+}
+
+// Validate 校验用户元数据，key不能为空且不能使用保留前缀，key和value都不能包含报文使用的分隔符
+func Validate(key, val string) error {
+	if key == "" {
+		return fmt.Errorf("micro：元数据key不能为空")
+	}
+
+	if IsReserved(key) {
+		return fmt.Errorf("micro：元数据key %q 使用了保留前缀 %s", key, ReservedPrefix)
+	}
+
+	if strings.ContainsAny(key, "\r\n") || strings.ContainsAny(val, "\r\n") {
+		return fmt.Errorf("micro：元数据 %q 不能包含\\r或者\\n", key)
+	}
+
+	return nil
+}
+
+type outgoingKey struct{}
+
+type incomingKey struct{}
+
+// NewOutgoingContext 设置客户端需要发送的元数据，会覆盖ctx中已有的元数据
+func NewOutgoingContext(ctx context.Context, md MD) context.Context {
+	return context.WithValue(ctx, outgoingKey{}, md)
+}
+
+// AppendOutgoing 以k1, v1, k2, v2...的形式追加客户端需要发送的元数据，参数个数为奇数时panic
+// 追加时会复制已有的元数据，不会影响父ctx
+func AppendOutgoing(ctx context.Context, kv ...string) context.Context {
+	added := Pairs(kv...)
+
+	md, ok := FromOutgoing(ctx)
+
+	if ok {
+		md = md.Copy()
+	} else {
+		md = make(MD, len(added))
+	}
+
+	for k, v := range added {
+		md[k] = v
+	}
+
+	return NewOutgoingContext(ctx, md)
+}
+
+// FromOutgoing 获取客户端需要发送的元数据，返回值不应该被修改
+func FromOutgoing(ctx context.Context) (MD, bool) {
+	md, ok := ctx.Value(outgoingKey{}).(MD)
+	return md, ok
+}
+
+// NewIncomingContext 设置服务端收到的元数据，由框架调用
+func NewIncomingContext(ctx context.Context, md MD) context.Context {
+	return context.WithValue(ctx, incomingKey{}, md)
+}
+
+// FromIncoming 获取服务端收到的元数据，其中不包含框架保留的key，返回值不应该被修改
+func FromIncoming(ctx context.Context) (MD, bool) {
+	md, ok := ctx.Value(incomingKey{}).(MD)
+	return md, ok
+}
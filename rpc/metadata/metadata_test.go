@@ -0,0 +1,78 @@
+package metadata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendOutgoing(t *testing.T) {
+	ctx := AppendOutgoing(context.Background(), "k1", "v1")
+
+	child := AppendOutgoing(ctx, "k2", "v2", "k1", "v3")
+
+	md, ok := FromOutgoing(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, MD{"k1": "v1"}, md)
+
+	md, ok = FromOutgoing(child)
+	assert.True(t, ok)
+	assert.Equal(t, MD{"k1": "v3", "k2": "v2"}, md)
+
+	assert.Panics(t, func() {
+		AppendOutgoing(ctx, "k1")
+	})
+}
+
+func TestFromIncoming(t *testing.T) {
+	_, ok := FromIncoming(context.Background())
+	assert.False(t, ok)
+
+	ctx := NewIncomingContext(context.Background(), Pairs("k1", "v1"))
+
+	md, ok := FromIncoming(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "v1", md.Get("k1"))
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		key     string
+		val     string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			key:  "trace-id",
+			val:  "123",
+		},
+		{
+			name:    "empty key",
+			wantErr: "micro：元数据key不能为空",
+		},
+		{
+			name:    "reserved",
+			key:     "sys_timeout",
+			wantErr: `micro：元数据key "sys_timeout" 使用了保留前缀 sys_`,
+		},
+		{
+			name:    "splitter",
+			key:     "k1",
+			val:     "v1\nk2\rv2",
+			wantErr: `micro：元数据 "k1" 不能包含\r或者\n`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.key, tc.val)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
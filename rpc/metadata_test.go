@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/metadata"
+)
+
+func TestMetadata(t *testing.T) {
+	backend := NewEndPoint(":8081")
+	backend.Register(&metaService{name: "backend"})
+
+	constructor := NewProxyConstructor()
+
+	frontend := NewEndPoint(":8082", WithForwardMeta("tenant"))
+	frontend.Register(&metaService{
+		name:   "frontend",
+		caller: constructor.NewProxyCaller(backend),
+	})
+
+	caller := constructor.NewProxyCaller(frontend)
+
+	ctx := metadata.AppendOutgoing(context.Background(), "tenant", "t1", "user", "u1")
+
+	// frontend只透传tenant，不透传user
+	res, err := Invoke[UserReq, UserResp](ctx, caller, "frontend", "Echo", &UserReq{})
+	require.NoError(t, err)
+	assert.Equal(t, "frontend[tenant=t1,user=u1] backend[tenant=t1]", res.Content)
+
+	ctx = metadata.AppendOutgoing(context.Background(), "sys_oneway", "true")
+	_, err = Invoke[UserReq, UserResp](ctx, caller, "frontend", "Echo", &UserReq{})
+	assert.EqualError(t, err, `micro：元数据key "sys_oneway" 使用了保留前缀 sys_`)
+}
+
+type metaService struct {
+	name   string
+	caller *Caller
+}
+
+// Echo 返回收到的元数据，如果有下游服务则同时返回下游服务收到的元数据
+func (m *metaService) Echo(ctx context.Context, req *UserReq) (*UserResp, error) {
+	md, _ := metadata.FromIncoming(ctx)
+
+	kvs := make([]string, 0, len(md))
+	for k, v := range md {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+
+	content := m.name + "[" + strings.Join(kvs, ",") + "]"
+
+	if m.caller != nil {
+		res, err := Invoke[UserReq, UserResp](ctx, m.caller, "backend", "Echo", req)
+		if err != nil {
+			return nil, err
+		}
+		content += " " + res.Content
+	}
+
+	return &UserResp{Content: content}, nil
+}
+
+func (m *metaService) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: m.name,
+	}
+}
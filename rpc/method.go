@@ -0,0 +1,112 @@
+package rpc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// serviceMethod 注册服务时预先解析的服务方法，处理请求时不再需要通过反射查找方法以及参数类型
+type serviceMethod struct {
+	// fn 绑定了接收者的方法
+	fn reflect.Value
+	// argType以及replyType为请求以及响应的指针类型，argElem为请求指向的类型，用于创建请求
+	argType   reflect.Type
+	argElem   reflect.Type
+	replyType reflect.Type
+	// timeout 服务端处理请求的超时时间，来自MethodOptioner，为0时只使用客户端传递的超时时间
+	timeout time.Duration
+}
+
+// rejectedMethod 不能被远程调用的导出方法以及原因
+type rejectedMethod struct {
+	name   string
+	reason string
+}
+
+// MethodOptioner 服务可以实现该接口声明方法在服务端的选项，key为方法名，value的格式与rpc tag相同，注册服务时解析
+// 支持的选项如下:
+//
+//	timeout=500ms   服务端处理请求的超时时间，与客户端传递的超时时间同时生效
+type MethodOptioner interface {
+	MethodOptions() map[string]string
+}
+
+// newMethodTable 解析服务所有形如 func(context.Context, *Req) (*Resp, error) 的方法，
+// 返回以方法名为key的方法表、按照字典序排列的方法名以及签名不符合要求的方法，
+// Service以及MethodOptioner接口自身的方法不会被当作签名不符合要求的方法
+func newMethodTable(val reflect.Value) (map[string]*serviceMethod, []string, []rejectedMethod) {
+	typ := val.Type()
+
+	methods := make(map[string]*serviceMethod, typ.NumMethod())
+	names := make([]string, 0, typ.NumMethod())
+	var rejected []rejectedMethod
+
+	// 反射得到的方法已经按照方法名排序
+	for i := 0; i < typ.NumMethod(); i++ {
+		fn := val.Method(i)
+		ft := fn.Type()
+		name := typ.Method(i).Name
+
+		if reason := validateFunc(ft); reason != "" {
+			if !isServiceInterfaceMethod(name) {
+				rejected = append(rejected, rejectedMethod{name: name, reason: reason})
+			}
+			continue
+		}
+
+		methods[name] = &serviceMethod{
+			fn:        fn,
+			argType:   ft.In(1),
+			argElem:   ft.In(1).Elem(),
+			replyType: ft.Out(0),
+		}
+		names = append(names, name)
+	}
+
+	return methods, names, rejected
+}
+
+// isServiceInterfaceMethod Service以及MethodOptioner接口的方法
+func isServiceInterfaceMethod(name string) bool {
+	return name == "Info" || name == "MethodOptions"
+}
+
+// applyMethodOptions 解析服务声明的方法选项并记录到方法表中，方法不存在或者选项不合法时返回错误
+func applyMethodOptions(service string, methods map[string]*serviceMethod, options map[string]string) error {
+	for name, tag := range options {
+		m, ok := methods[name]
+		if !ok {
+			return fmt.Errorf("micro：服务 %q 没有可以远程调用的方法 %q，不能设置方法选项", service, name)
+		}
+		if reason := parseServerMethodTag(m, tag); reason != "" {
+			return fmt.Errorf("micro：服务 %q 方法 %q 的选项不合法, %s", service, name, reason)
+		}
+	}
+	return nil
+}
+
+// parseServerMethodTag 解析服务端的方法选项，返回不合法的原因，合法则返回空字符串
+func parseServerMethodTag(m *serviceMethod, tag string) string {
+	if tag == "" {
+		return ""
+	}
+
+	for _, item := range strings.Split(tag, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(item), "=")
+
+		switch key {
+		case "timeout":
+			timeout, err := time.ParseDuration(val)
+			if err != nil || timeout <= 0 {
+				return fmt.Sprintf("超时时间 %q 不合法", val)
+			}
+			m.timeout = timeout
+		default:
+			return fmt.Sprintf("不支持的选项 %q", key)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,213 @@
+package rpc
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/logger"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/serialize/json"
+	"github.com/uzziahlin/transport/rpc/status"
+)
+
+// methodTableService 只有Query是服务方法
+type methodTableService struct{}
+
+func (s *methodTableService) Query(ctx context.Context, req *UserReq) (*UserResp, error) {
+	return &UserResp{}, nil
+}
+
+func (s *methodTableService) Info() ServiceInfo {
+	return ServiceInfo{ServiceName: "query-service"}
+}
+
+func (s *methodTableService) Ping(req *UserReq) (*UserResp, error) {
+	return &UserResp{}, nil
+}
+
+func (s *methodTableService) Stream(ctx context.Context, req chan *UserReq) (*UserResp, error) {
+	return &UserResp{}, nil
+}
+
+func TestNewMethodTable(t *testing.T) {
+	testCases := []struct {
+		name      string
+		service   Service
+		wantNames []string
+		// wantRejected 签名不符合要求的方法，不包括Info
+		wantRejected []string
+	}{
+		{
+			name:      "user service",
+			service:   &UserServiceImpl{},
+			wantNames: []string{"GetById"},
+		},
+		{
+			name:         "skip invalid methods",
+			service:      &methodTableService{},
+			wantNames:    []string{"Query"},
+			wantRejected: []string{"Ping", "Stream"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			methods, names, rejected := newMethodTable(reflect.ValueOf(tc.service))
+			assert.Equal(t, tc.wantNames, names)
+
+			var rejectedNames []string
+			for _, m := range rejected {
+				assert.NotEmpty(t, m.reason)
+				rejectedNames = append(rejectedNames, m.name)
+			}
+			assert.Equal(t, tc.wantRejected, rejectedNames)
+			assert.Len(t, methods, len(tc.wantNames))
+
+			for _, name := range names {
+				m := methods[name]
+				assert.Equal(t, reflect.TypeOf(&UserReq{}), m.argType)
+				assert.Equal(t, reflect.TypeOf(UserReq{}), m.argElem)
+				assert.Equal(t, reflect.TypeOf(&UserResp{}), m.replyType)
+			}
+		})
+	}
+}
+
+func TestEndPoint_RegisterRejectedMethods(t *testing.T) {
+	buf := &syncBuffer{}
+	endpoint := NewEndPoint("", WithServerLogger(logger.NewStdLogger(log.New(buf, "", 0), logger.LevelInfo)))
+	require.NoError(t, endpoint.Register(&methodTableService{}))
+
+	// 注册时输出签名不符合要求的方法
+	assert.Contains(t, buf.String(), "method=Ping")
+	assert.Contains(t, buf.String(), "method=Stream")
+	assert.NotContains(t, buf.String(), "method=Info")
+
+	stub, err := endpoint.service("query-service", "", "")
+	require.NoError(t, err)
+
+	// 调用时返回方法不能被调用的原因
+	_, err = stub.invoke(context.Background(), newMethodRequest(t, "query-service", "Ping"), newCallStats())
+	assert.Equal(t, status.Unimplemented, status.CodeOf(err))
+	assert.Contains(t, err.Error(), "必须有且只有两个参数")
+
+	_, err = stub.invoke(context.Background(), newMethodRequest(t, "query-service", "Missing"), newCallStats())
+	assert.Equal(t, status.Unimplemented, status.CodeOf(err))
+	assert.Contains(t, err.Error(), "没有方法")
+}
+
+// optionService 通过MethodOptioner声明方法选项，Deadline返回方法中ctx的剩余时间
+type optionService struct {
+	options map[string]string
+}
+
+func (s *optionService) Deadline(ctx context.Context, req *UserReq) (*UserResp, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return &UserResp{Content: "none"}, nil
+	}
+	return &UserResp{Content: time.Until(deadline).Round(time.Second).String()}, nil
+}
+
+func (s *optionService) MethodOptions() map[string]string {
+	return s.options
+}
+
+func (s *optionService) Info() ServiceInfo {
+	return ServiceInfo{ServiceName: "option-service"}
+}
+
+func TestEndPoint_MethodOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options map[string]string
+		// ctxTimeout 客户端传递的超时时间
+		ctxTimeout time.Duration
+		want       string
+		wantErr    bool
+	}{
+		{
+			name: "no options",
+			want: "none",
+		},
+		{
+			name:    "timeout",
+			options: map[string]string{"Deadline": "timeout=3s"},
+			want:    "3s",
+		},
+		{
+			name:       "client deadline earlier",
+			options:    map[string]string{"Deadline": "timeout=3s"},
+			ctxTimeout: time.Second,
+			want:       "1s",
+		},
+		{
+			name:    "unknown method",
+			options: map[string]string{"Missing": "timeout=3s"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid timeout",
+			options: map[string]string{"Deadline": "timeout=abc"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported option",
+			options: map[string]string{"Deadline": "retry=1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := NewEndPoint("")
+			err := endpoint.Register(&optionService{options: tc.options})
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.NotContains(t, endpoint.serviceNames(), "option-service")
+				return
+			}
+			require.NoError(t, err)
+
+			stub, err := endpoint.service("option-service", "", "")
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			if tc.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.ctxTimeout)
+				defer cancel()
+			}
+
+			data, err := stub.invoke(ctx, newMethodRequest(t, "option-service", "Deadline"), newCallStats())
+			require.NoError(t, err)
+
+			resp := &UserResp{}
+			require.NoError(t, (&json.Serializer{}).Deserialize(data, resp))
+			assert.Equal(t, tc.want, resp.Content)
+		})
+	}
+}
+
+func newMethodRequest(t *testing.T, service, method string) *message.Request {
+	data, err := (&json.Serializer{}).Serialize(&UserReq{Id: "1"})
+	require.NoError(t, err)
+
+	return &message.Request{
+		RequestHeader: message.RequestHeader{
+			Header: message.Header{
+				MessageId:  1,
+				Serializer: (&json.Serializer{}).Code(),
+			},
+			ServiceName: service,
+			MethodName:  method,
+			Meta:        map[string]string{},
+		},
+		Data: data,
+	}
+}
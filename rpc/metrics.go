@@ -0,0 +1,168 @@
+package rpc
+
+import (
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/metrics"
+	"time"
+)
+
+// callStats 一次调用中请求以及响应在压缩前后的大小，-1代表未知，例如oneway调用没有响应
+type callStats struct {
+	reqSize      int
+	reqWireSize  int
+	respSize     int
+	respWireSize int
+}
+
+func newCallStats() *callStats {
+	return &callStats{
+		reqSize:      -1,
+		reqWireSize:  -1,
+		respSize:     -1,
+		respWireSize: -1,
+	}
+}
+
+const (
+	statusOK    = "ok"
+	statusError = "error"
+
+	// unknownLabel 服务端指标中未注册的服务或者方法使用的标签值，
+	// 避免客户端通过任意的服务名以及方法名构造无限多的指标
+	unknownLabel = "unknown"
+)
+
+// rpcMetrics 客户端或者服务端的调用指标，nil代表没有开启指标统计
+type rpcMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	reqSize  *metrics.HistogramVec
+	respSize *metrics.HistogramVec
+	// panics 只有服务端会统计
+	panics *metrics.CounterVec
+}
+
+// newRpcMetrics side为client或者server，作为指标名的前缀
+func newRpcMetrics(reg *metrics.Registry, side string) *rpcMetrics {
+	if reg == nil {
+		return nil
+	}
+
+	prefix := "rpc_" + side + "_"
+
+	return &rpcMetrics{
+		requests: reg.Counter(prefix+"requests_total",
+			"Total number of RPCs completed, partitioned by service, method and status.",
+			"service", "method", "status"),
+		duration: reg.Histogram(prefix+"duration_seconds",
+			"Latency of RPCs in seconds.",
+			metrics.DefDurationBuckets, "service", "method"),
+		reqSize: reg.Histogram(prefix+"request_size_bytes",
+			"Size of RPC request payloads, stage is raw before compression or wire after compression.",
+			metrics.DefSizeBuckets, "service", "method", "stage"),
+		respSize: reg.Histogram(prefix+"response_size_bytes",
+			"Size of RPC response payloads, stage is raw before compression or wire after compression.",
+			metrics.DefSizeBuckets, "service", "method", "stage"),
+	}
+}
+
+func (m *rpcMetrics) observe(service, method string, start time.Time, stats *callStats, err error) {
+	if m == nil {
+		return
+	}
+
+	status := statusOK
+
+	if err != nil {
+		status = statusError
+	}
+
+	m.requests.WithLabelValues(service, method, status).Inc()
+	m.duration.WithLabelValues(service, method).Observe(time.Since(start).Seconds())
+
+	observeSize(m.reqSize, service, method, "raw", stats.reqSize)
+	observeSize(m.reqSize, service, method, "wire", stats.reqWireSize)
+	observeSize(m.respSize, service, method, "raw", stats.respSize)
+	observeSize(m.respSize, service, method, "wire", stats.respWireSize)
+}
+
+// observePanic 记录一次服务方法的panic
+func (m *rpcMetrics) observePanic(service, method string) {
+	if m == nil || m.panics == nil {
+		return
+	}
+
+	m.panics.WithLabelValues(service, method).Inc()
+}
+
+func observeSize(h *metrics.HistogramVec, service, method, stage string, size int) {
+	if size >= 0 {
+		h.WithLabelValues(service, method, stage).Observe(float64(size))
+	}
+}
+
+// registerPoolMetrics 注册连接池的活跃连接数、空闲连接数以及等待获取连接的g的数量
+// 同一个地址下的多个连接池的数值会被累加，连接池关闭时需要调用返回的函数注销指标
+func registerPoolMetrics(reg *metrics.Registry, addr string, pool *ConnPool[*clientConn]) (unregister func()) {
+	gauges := []struct {
+		name string
+		help string
+		fn   func(stats PoolStats) int
+	}{
+		{
+			name: "rpc_client_pool_active_conns",
+			help: "Number of connections created by the client pool, including idle ones.",
+			fn: func(stats PoolStats) int {
+				return stats.Active
+			},
+		},
+		{
+			name: "rpc_client_pool_idle_conns",
+			help: "Number of idle connections in the client pool.",
+			fn: func(stats PoolStats) int {
+				return stats.Idle
+			},
+		},
+		{
+			name: "rpc_client_pool_waiting",
+			help: "Number of callers waiting for a connection from the client pool.",
+			fn: func(stats PoolStats) int {
+				return stats.Waiting
+			},
+		},
+	}
+
+	removes := make([]func(), 0, len(gauges))
+
+	for _, g := range gauges {
+		fn := g.fn
+		removes = append(removes, reg.GaugeFunc(g.name, g.help, "addr").WithLabelValues(addr).Add(func() float64 {
+			return float64(fn(pool.Stats()))
+		}))
+	}
+
+	return func() {
+		for _, remove := range removes {
+			remove()
+		}
+	}
+}
+
+// metricLabels 返回服务端指标使用的服务名以及方法名，只有已经注册的服务以及方法才使用请求中的名字
+func (e *EndPoint) metricLabels(req *message.Request) (service, method string) {
+	e.servicesMu.RLock()
+	defer e.servicesMu.RUnlock()
+
+	stubs := e.services[req.ServiceName]
+	if len(stubs) == 0 {
+		return unknownLabel, unknownLabel
+	}
+
+	for _, stub := range stubs {
+		if _, ok := stub.methods[req.MethodName]; ok {
+			return req.ServiceName, req.MethodName
+		}
+	}
+
+	return req.ServiceName, unknownLabel
+}
@@ -0,0 +1,221 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"sync"
+)
+
+var (
+	// DefDurationBuckets 默认的耗时桶，单位为秒
+	DefDurationBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// DefSizeBuckets 默认的大小桶，单位为字节
+	DefSizeBuckets = []float64{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+)
+
+// Counter 单调递增的计数器
+type Counter struct {
+	mu  sync.Mutex
+	val float64
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add 增加计数，v必须为非负数
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter 不能减少")
+	}
+	c.mu.Lock()
+	c.val += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.val
+}
+
+type CounterVec struct {
+	*vec[Counter]
+}
+
+func newCounterVec(d *desc) *CounterVec {
+	return &CounterVec{
+		vec: newVec(d, func() *Counter {
+			return &Counter{}
+		}),
+	}
+}
+
+// WithLabelValues 获取标签值对应的counter，标签值个数与标签名个数不一致时panic
+func (c *CounterVec) WithLabelValues(labelValues ...string) *Counter {
+	return c.with(labelValues)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	for _, ch := range c.sorted() {
+		writeSample(w, c.d.name, c.d.labelNames, ch.labelValues, "", "", ch.m.Value())
+	}
+}
+
+// Histogram 统计样本的分布
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+			break
+		}
+	}
+
+	h.sum += v
+	h.count++
+}
+
+// Snapshot 返回各个桶的累计数量，以及样本总和以及样本个数
+func (h *Histogram) Snapshot() (cumulative []uint64, sum float64, count uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cumulative = make([]uint64, len(h.counts))
+
+	var acc uint64
+	for i, c := range h.counts {
+		acc += c
+		cumulative[i] = acc
+	}
+
+	return cumulative, h.sum, h.count
+}
+
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
+
+func newHistogramVec(d *desc, buckets []float64) *HistogramVec {
+	bs := make([]float64, len(buckets))
+	copy(bs, buckets)
+
+	return &HistogramVec{
+		buckets: bs,
+		vec: newVec(d, func() *Histogram {
+			return &Histogram{
+				buckets: bs,
+				counts:  make([]uint64, len(bs)),
+			}
+		}),
+	}
+}
+
+// WithLabelValues 获取标签值对应的histogram，标签值个数与标签名个数不一致时panic
+func (h *HistogramVec) WithLabelValues(labelValues ...string) *Histogram {
+	return h.with(labelValues)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	for _, ch := range h.sorted() {
+		cumulative, sum, count := ch.m.Snapshot()
+		for i, upper := range h.buckets {
+			writeSample(w, h.d.name+"_bucket", h.d.labelNames, ch.labelValues, "le", formatFloat(upper), float64(cumulative[i]))
+		}
+		writeSample(w, h.d.name+"_bucket", h.d.labelNames, ch.labelValues, "le", formatFloat(math.Inf(1)), float64(count))
+		writeSample(w, h.d.name+"_sum", h.d.labelNames, ch.labelValues, "", "", sum)
+		writeSample(w, h.d.name+"_count", h.d.labelNames, ch.labelValues, "", "", float64(count))
+	}
+}
+
+// GaugeFunc 在输出时通过函数计算取值的gauge，同一组标签值下的多个函数取值会被累加
+type GaugeFunc struct {
+	mu  sync.Mutex
+	fns []*gaugeFn
+}
+
+// gaugeFn 通过指针区分同一个GaugeFunc下的多个函数
+type gaugeFn struct {
+	fn func() float64
+}
+
+// Add 添加一个取值函数，返回的remove用于移除该函数，例如函数引用的对象被关闭时，
+// 移除之后函数不会再被调用，也不会继续持有函数引用的对象。remove可以重复调用
+func (g *GaugeFunc) Add(fn func() float64) (remove func()) {
+	e := &gaugeFn{fn: fn}
+
+	g.mu.Lock()
+	g.fns = append(g.fns, e)
+	g.mu.Unlock()
+
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		// Value会在锁外遍历之前的切片，这里重新分配而不是原地修改
+		fns := make([]*gaugeFn, 0, len(g.fns))
+		for _, f := range g.fns {
+			if f != e {
+				fns = append(fns, f)
+			}
+		}
+		g.fns = fns
+	}
+}
+
+func (g *GaugeFunc) Value() float64 {
+	g.mu.Lock()
+	fns := g.fns
+	g.mu.Unlock()
+
+	var res float64
+	for _, f := range fns {
+		res += f.fn()
+	}
+	return res
+}
+
+// empty 所有函数都已经被移除
+func (g *GaugeFunc) empty() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.fns) == 0
+}
+
+type GaugeFuncVec struct {
+	*vec[GaugeFunc]
+}
+
+func newGaugeFuncVec(d *desc) *GaugeFuncVec {
+	return &GaugeFuncVec{
+		vec: newVec(d, func() *GaugeFunc {
+			return &GaugeFunc{}
+		}),
+	}
+}
+
+// WithLabelValues 获取标签值对应的gauge，标签值个数与标签名个数不一致时panic
+func (g *GaugeFuncVec) WithLabelValues(labelValues ...string) *GaugeFunc {
+	return g.with(labelValues)
+}
+
+func (g *GaugeFuncVec) write(w *bufio.Writer) {
+	for _, ch := range g.sorted() {
+		// 所有函数都已经被移除的标签值不再输出
+		if ch.m.empty() {
+			continue
+		}
+		writeSample(w, g.d.name, g.d.labelNames, ch.labelValues, "", "", ch.m.Value())
+	}
+}
@@ -0,0 +1,119 @@
+// Package metrics 一个轻量的指标库，支持counter、histogram以及gauge，并以Prometheus文本格式输出
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+const (
+	typeCounter   = "counter"
+	typeHistogram = "histogram"
+	typeGauge     = "gauge"
+)
+
+// metric 所有指标的公共抽象
+type metric interface {
+	desc() *desc
+	// write 按照Prometheus文本格式输出所有的样本
+	write(w *bufio.Writer)
+}
+
+type desc struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+}
+
+// Registry 指标的注册中心，指标通过名称唯一确定
+// 重复获取同名同类型的指标会返回已经存在的指标，方便客户端和服务端共享同一个Registry
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]metric, 16),
+	}
+}
+
+// Counter 获取或者创建一个counter
+func (r *Registry) Counter(name, help string, labelNames ...string) *CounterVec {
+	m := r.getOrCreate(name, typeCounter, labelNames, func() metric {
+		return newCounterVec(&desc{name: name, help: help, typ: typeCounter, labelNames: labelNames})
+	})
+	return m.(*CounterVec)
+}
+
+// Histogram 获取或者创建一个histogram，buckets为各个桶的上界，需要按照升序排列
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	m := r.getOrCreate(name, typeHistogram, labelNames, func() metric {
+		return newHistogramVec(&desc{name: name, help: help, typ: typeHistogram, labelNames: labelNames}, buckets)
+	})
+	return m.(*HistogramVec)
+}
+
+// GaugeFunc 获取或者创建一个在输出时通过函数计算取值的gauge
+func (r *Registry) GaugeFunc(name, help string, labelNames ...string) *GaugeFuncVec {
+	m := r.getOrCreate(name, typeGauge, labelNames, func() metric {
+		return newGaugeFuncVec(&desc{name: name, help: help, typ: typeGauge, labelNames: labelNames})
+	})
+	return m.(*GaugeFuncVec)
+}
+
+func (r *Registry) getOrCreate(name, typ string, labelNames []string, create func() metric) metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m, ok := r.metrics[name]; ok {
+		d := m.desc()
+		if d.typ != typ || len(d.labelNames) != len(labelNames) {
+			panic(fmt.Sprintf("metrics: 指标 %s 已经以不同的类型或者标签注册", name))
+		}
+		return m
+	}
+
+	m := create()
+	r.metrics[name] = m
+
+	return m
+}
+
+// WritePrometheus 按照Prometheus文本格式输出所有指标，指标按照名称排序
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	ms := make([]metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		ms = append(ms, m)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].desc().name < ms[j].desc().name
+	})
+
+	bw := bufio.NewWriter(w)
+
+	for _, m := range ms {
+		d := m.desc()
+		_, _ = fmt.Fprintf(bw, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+		_, _ = fmt.Fprintf(bw, "# TYPE %s %s\n", d.name, d.typ)
+		m.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+// Handler 返回以Prometheus文本格式输出所有指标的http.Handler
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WritePrometheus(w)
+	})
+}
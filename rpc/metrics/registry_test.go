@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WritePrometheus(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.Counter("rpc_requests_total", "Total number of requests.", "service", "status")
+	requests.WithLabelValues("user-service", "ok").Inc()
+	requests.WithLabelValues("user-service", "ok").Add(2)
+	requests.WithLabelValues("order\"service", "error").Inc()
+
+	duration := r.Histogram("rpc_duration_seconds", "Request latency.\nIn seconds.", []float64{0.1, 1})
+	duration.WithLabelValues().Observe(0.05)
+	duration.WithLabelValues().Observe(0.5)
+	duration.WithLabelValues().Observe(5)
+
+	conns := r.GaugeFunc("pool_active_conns", "Active connections.", "addr")
+	conns.WithLabelValues("localhost:8081").Add(func() float64 { return 2 })
+	conns.WithLabelValues("localhost:8081").Add(func() float64 { return 3 })
+
+	// 重复获取会返回同一个指标
+	assert.Same(t, requests, r.Counter("rpc_requests_total", "Total number of requests.", "service", "status"))
+	assert.Panics(t, func() {
+		r.Histogram("rpc_requests_total", "", nil, "service", "status")
+	})
+	assert.Panics(t, func() {
+		requests.WithLabelValues("user-service")
+	})
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, r.WritePrometheus(buf))
+
+	assert.Equal(t, `# HELP pool_active_conns Active connections.
+# TYPE pool_active_conns gauge
+pool_active_conns{addr="localhost:8081"} 5
+# HELP rpc_duration_seconds Request latency.\nIn seconds.
+# TYPE rpc_duration_seconds histogram
+rpc_duration_seconds_bucket{le="0.1"} 1
+rpc_duration_seconds_bucket{le="1"} 2
+rpc_duration_seconds_bucket{le="+Inf"} 3
+rpc_duration_seconds_sum 5.55
+rpc_duration_seconds_count 3
+# HELP rpc_requests_total Total number of requests.
+# TYPE rpc_requests_total counter
+rpc_requests_total{service="order\"service",status="error"} 1
+rpc_requests_total{service="user-service",status="ok"} 3
+`, buf.String())
+}
+
+func TestGaugeFunc_Remove(t *testing.T) {
+	r := NewRegistry()
+
+	conns := r.GaugeFunc("pool_active_conns", "Active connections.", "addr")
+	remove1 := conns.WithLabelValues("localhost:8081").Add(func() float64 { return 2 })
+	remove2 := conns.WithLabelValues("localhost:8081").Add(func() float64 { return 3 })
+
+	remove1()
+	// 重复移除不影响其他函数
+	remove1()
+	assert.Equal(t, float64(3), conns.WithLabelValues("localhost:8081").Value())
+
+	remove2()
+
+	// 所有函数都被移除之后不再输出该标签值
+	buf := &bytes.Buffer{}
+	require.NoError(t, r.WritePrometheus(buf))
+	assert.Equal(t, `# HELP pool_active_conns Active connections.
+# TYPE pool_active_conns gauge
+`, buf.String())
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("rpc_requests_total", "Total number of requests.").WithLabelValues().Inc()
+
+	recorder := httptest.NewRecorder()
+	r.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "rpc_requests_total 1\n")
+}
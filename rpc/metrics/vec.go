@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// labelKey 将标签值拼接为map的key，\xff不会出现在合法的utf8字符串中
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// vec 按照标签值管理子指标
+type vec[T any] struct {
+	d        *desc
+	mu       sync.RWMutex
+	children map[string]*child[T]
+	create   func() *T
+}
+
+type child[T any] struct {
+	labelValues []string
+	m           *T
+}
+
+func newVec[T any](d *desc, create func() *T) *vec[T] {
+	return &vec[T]{
+		d:        d,
+		children: make(map[string]*child[T], 8),
+		create:   create,
+	}
+}
+
+func (v *vec[T]) desc() *desc {
+	return v.d
+}
+
+func (v *vec[T]) with(labelValues []string) *T {
+	if len(labelValues) != len(v.d.labelNames) {
+		panic(fmt.Sprintf("metrics: 指标 %s 需要 %d 个标签值, 实际为 %d 个", v.d.name, len(v.d.labelNames), len(labelValues)))
+	}
+
+	key := labelKey(labelValues)
+
+	v.mu.RLock()
+	c, ok := v.children[key]
+	v.mu.RUnlock()
+
+	if ok {
+		return c.m
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if c, ok = v.children[key]; ok {
+		return c.m
+	}
+
+	vals := make([]string, len(labelValues))
+	copy(vals, labelValues)
+
+	c = &child[T]{
+		labelValues: vals,
+		m:           v.create(),
+	}
+
+	v.children[key] = c
+
+	return c.m
+}
+
+// sorted 按照标签值排序返回所有子指标，保证输出稳定
+func (v *vec[T]) sorted() []*child[T] {
+	v.mu.RLock()
+	res := make([]*child[T], 0, len(v.children))
+	for _, c := range v.children {
+		res = append(res, c)
+	}
+	v.mu.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		return labelKey(res[i].labelValues) < labelKey(res[j].labelValues)
+	})
+
+	return res
+}
+
+// writeSample 输出一行样本，extra为额外的标签，例如histogram的le
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, val float64) {
+	w.WriteString(name)
+
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, ln := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, ln, labelValues[i])
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(val))
+	w.WriteByte('\n')
+}
+
+func writeLabel(w *bufio.Writer, name, value string) {
+	w.WriteString(name)
+	w.WriteString(`="`)
+	w.WriteString(escapeLabelValue(value))
+	w.WriteByte('"')
+}
+
+var (
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/compress"
+	"github.com/uzziahlin/transport/rpc/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	endpoint := NewEndPoint(":8081", WithServerMetrics(reg))
+
+	service := &UserServiceErr{}
+	service.Msg = "this is the msg"
+	service.Err = "this is the err"
+
+	endpoint.Register(&UserServiceImpl{})
+
+	errEndpoint := NewEndPoint(":8082", WithServerMetrics(reg))
+	errEndpoint.Register(service)
+
+	constructor := NewProxyConstructor(WithMetrics(reg))
+
+	ctx := compress.Context(context.Background(), compress.GZIP)
+
+	for i := 0; i < 2; i++ {
+		_, err := Invoke[UserReq, UserResp](ctx, constructor.NewProxyCaller(endpoint), "user-service", "GetById", &UserReq{Id: "metrics"})
+		require.NoError(t, err)
+	}
+
+	_, err := Invoke[UserReq, UserResp](context.Background(), constructor.NewProxyCaller(errEndpoint), "user-service", "GetById", &UserReq{Id: "metrics"})
+	require.Error(t, err)
+
+	// 连接池的指标在创建远端代理时注册
+	constructor.NewCaller("localhost:8081")
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, reg.WritePrometheus(buf))
+	out := buf.String()
+
+	for _, side := range []string{"client", "server"} {
+		assert.Contains(t, out, `rpc_`+side+`_requests_total{service="user-service",method="GetById",status="ok"} 2`)
+		assert.Contains(t, out, `rpc_`+side+`_requests_total{service="user-service",method="GetById",status="error"} 1`)
+		assert.Contains(t, out, `rpc_`+side+`_duration_seconds_count{service="user-service",method="GetById"} 3`)
+		// 请求在压缩前的大小为 {"Id":"metrics"} 的长度
+		assert.Contains(t, out, `rpc_`+side+`_request_size_bytes_sum{service="user-service",method="GetById",stage="raw"} 48`)
+		assert.Contains(t, out, `rpc_`+side+`_request_size_bytes_count{service="user-service",method="GetById",stage="wire"} 3`)
+		assert.Contains(t, out, `rpc_`+side+`_response_size_bytes_count{service="user-service",method="GetById",stage="raw"} 3`)
+	}
+
+	assert.Contains(t, out, `rpc_client_pool_active_conns{addr="localhost:8081"} 0`)
+	assert.Contains(t, out, `rpc_client_pool_idle_conns{addr="localhost:8081"} 0`)
+	assert.Contains(t, out, `rpc_client_pool_waiting{addr="localhost:8081"} 0`)
+}
+
+func TestMetrics_UnknownLabels(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	endpoint := NewEndPoint(":8081", WithServerMetrics(reg))
+	endpoint.Register(&UserServiceImpl{})
+
+	caller := NewProxyConstructor().NewProxyCaller(endpoint)
+
+	// 不存在的服务以及方法不会使用请求中的名字作为标签值
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := Invoke[UserReq, UserResp](context.Background(), caller, "service-"+name, "GetById", &UserReq{})
+		require.Error(t, err)
+
+		_, err = Invoke[UserReq, UserResp](context.Background(), caller, "user-service", "Method"+name, &UserReq{})
+		require.Error(t, err)
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, reg.WritePrometheus(buf))
+	out := buf.String()
+
+	assert.Contains(t, out, `rpc_server_requests_total{service="unknown",method="unknown",status="error"} 3`)
+	assert.Contains(t, out, `rpc_server_requests_total{service="user-service",method="unknown",status="error"} 3`)
+	assert.NotContains(t, out, "service-a")
+	assert.NotContains(t, out, "Methoda")
+}
+
+func TestMetrics_PoolUnregister(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	constructor := NewProxyConstructor(WithMetrics(reg))
+	constructor.NewCaller("localhost:8081")
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, reg.WritePrometheus(buf))
+	assert.Contains(t, buf.String(), `rpc_client_pool_active_conns{addr="localhost:8081"} 0`)
+
+	// 关闭之后连接池不再被指标引用，也不再输出
+	require.NoError(t, constructor.Close())
+
+	buf.Reset()
+	require.NoError(t, reg.WritePrometheus(buf))
+	assert.NotContains(t, buf.String(), `addr="localhost:8081"`)
+}
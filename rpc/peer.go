@@ -0,0 +1,28 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// Peer 服务端处理请求时对端的信息
+type Peer struct {
+	Addr net.Addr
+	// TLS 连接的TLS状态，没有使用TLS时为nil
+	TLS *tls.ConnectionState
+	// Identity 通过了证书校验的客户端身份，只有双向TLS时才有值
+	Identity *Identity
+}
+
+type peerKey struct{}
+
+func NewPeerContext(ctx context.Context, p *Peer) context.Context {
+	return context.WithValue(ctx, peerKey{}, p)
+}
+
+// PeerFromContext 获取对端的信息，服务方法可以通过它获取调用方的地址
+func PeerFromContext(ctx context.Context) (*Peer, bool) {
+	p, ok := ctx.Value(peerKey{}).(*Peer)
+	return p, ok
+}
@@ -2,6 +2,7 @@ package rpc
 
 import (
 	"context"
+	"github.com/uzziahlin/transport/rpc/errs"
 	"io"
 	"sync"
 	"time"
@@ -14,6 +15,10 @@ type Closer interface {
 type Pool[T Closer] interface {
 	Put(ctx context.Context, t T) error
 	Get(ctx context.Context) (T, error)
+	// Remove 关闭并丢弃一个不可再复用的连接，例如读写过程中被打断的连接
+	Remove(ctx context.Context, t T) error
+	// Close 关闭连接池，之后Get会返回errs.ErrClientClosed
+	Close() error
 }
 
 type Conn[T Closer] struct {
@@ -22,14 +27,36 @@ type Conn[T Closer] struct {
 }
 
 type ConnPool[T Closer] struct {
-	idleConns   chan *Conn[T]            // 空闲连接池
-	activeCnt   int                      // 目前活跃连接数
-	maxActive   int                      // 最大活跃连接数
-	maxIdleTime time.Duration            // 最大空闲时间
-	waitQ       map[uint64]chan *Conn[T] // 等待队列
-	factory     func() T                 // 工厂函数，定义了如何创建T
+	idleConns   chan *Conn[T]                        // 空闲连接池
+	activeCnt   int                                  // 目前活跃连接数
+	maxActive   int                                  // 最大活跃连接数
+	maxIdleTime time.Duration                        // 最大空闲时间
+	waitQ       map[uint64]chan *Conn[T]             // 等待队列
+	factory     func(ctx context.Context) (T, error) // 工厂函数，定义了如何创建T
 	mu          sync.Mutex
 	seq         uint64
+	closed      bool
+}
+
+// PoolStats 连接池的统计信息
+type PoolStats struct {
+	// Active 已经创建的连接数，包含空闲连接
+	Active int
+	// Idle 空闲连接数
+	Idle int
+	// Waiting 等待获取连接的g的数量
+	Waiting int
+}
+
+func (c *ConnPool[T]) Stats() PoolStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return PoolStats{
+		Active:  c.activeCnt,
+		Idle:    len(c.idleConns),
+		Waiting: len(c.waitQ),
+	}
 }
 
 // Put 将连接放回连接池
@@ -38,22 +65,19 @@ type ConnPool[T Closer] struct {
 // 如果无法放回空闲连接池，则关闭连接
 func (c *ConnPool[T]) Put(ctx context.Context, t T) error {
 	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// 先判断等待队列是否为空，不为空直接把连接交给对方
-	if len(c.waitQ) > 0 {
-		for k, v := range c.waitQ {
-			delete(c.waitQ, k)
-			// 此处应该解锁，避免阻塞死锁
-			c.mu.Unlock()
-
-			// 避免阻塞
-			select {
-			case v <- &Conn[T]{t: t}:
-			default:
-			}
+	if c.closed {
+		_ = t.Close()
+		c.activeCnt--
+		return nil
+	}
 
-			return nil
-		}
+	// 先判断等待队列是否为空，不为空直接把连接交给对方
+	// 等待队列的channel带有缓冲，在持有锁的情况下发送不会阻塞，
+	// 同时保证了等待超时的g在持有锁检查channel时不会漏掉信号
+	if c.handOff(&Conn[T]{t: t}) {
+		return nil
 	}
 
 	// 走到这说明等待队列为空， 则尝试放入空闲连接池
@@ -67,11 +91,16 @@ func (c *ConnPool[T]) Put(ctx context.Context, t T) error {
 		c.activeCnt--
 	}
 
-	c.mu.Unlock()
-
 	return nil
 }
 
+// Remove 关闭连接并释放其占用的活跃连接名额
+func (c *ConnPool[T]) Remove(ctx context.Context, t T) error {
+	err := t.Close()
+	c.release()
+	return err
+}
+
 // Get 从连接池获取连接
 // 先尝试从空闲连接池获取连接，如果能获取到，则返回
 // 如果无法从空闲连接池获取连接，则判断目前活跃连接数是否达到最大活跃连接数，如果没有，则创建一个新的连接
@@ -92,10 +121,7 @@ Loop:
 			// 如果拿到了，判断当前连接是否超过最大空闲时间，如果超过，则关闭
 			if conn.lastActiveTime.Add(c.maxIdleTime).Before(time.Now()) {
 				// 走到这里说明，连接超过最大空闲时间，则关闭
-				_ = conn.t.Close()
-				c.mu.Lock()
-				c.activeCnt--
-				c.mu.Unlock()
+				_ = c.Remove(ctx, conn.t)
 				continue
 			}
 			// 如果没超过最大空闲时间，则返回
@@ -108,11 +134,15 @@ Loop:
 
 	// 走到这里，说明空闲连接池里获取不到连接，则判断是否达到最大连接数
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return res, errs.ErrClientClosed
+	}
 	if c.activeCnt < c.maxActive {
-		res = c.factory()
+		// 先占用名额再创建连接，避免创建连接时持有锁
 		c.activeCnt++
 		c.mu.Unlock()
-		return res, nil
+		return c.create(ctx)
 	}
 
 	// 走到这里，说明超过最大活跃连接上线，则阻塞等待唤醒
@@ -126,21 +156,107 @@ Loop:
 
 	select {
 	case conn := <-q:
-		// 如果收到信号，假设不会过期
-		return conn.t, nil
+		return c.accept(ctx, conn)
 	case <-ctx.Done():
 		// 走到这里说明超时了，应该删除等待队列中
 		c.mu.Lock()
-		defer c.mu.Unlock()
 		delete(c.waitQ, seq)
+		c.mu.Unlock()
 
 		// 避免漏信号，如果收到应该转发出去
 		select {
 		case conn := <-q:
-			_ = c.Put(context.TODO(), conn.t)
+			if conn == nil {
+				c.release()
+			} else {
+				_ = c.Put(ctx, conn.t)
+			}
 		default:
 		}
 		return res, ctx.Err()
 	}
 
 }
+
+// Close 关闭连接池以及所有空闲连接，正在等待的g会收到名额后发现连接池已关闭
+func (c *ConnPool[T]) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	for {
+		select {
+		case conn := <-c.idleConns:
+			_ = conn.t.Close()
+			c.activeCnt--
+		default:
+			// 唤醒所有等待的g，由它们自行返回错误
+			for k, v := range c.waitQ {
+				delete(c.waitQ, k)
+				v <- nil
+				c.activeCnt++
+			}
+			return nil
+		}
+	}
+}
+
+// accept 处理从等待队列收到的信号
+// nil代表其他g释放了一个名额，需要自行创建连接
+func (c *ConnPool[T]) accept(ctx context.Context, conn *Conn[T]) (T, error) {
+	if conn == nil {
+		if c.isClosed() {
+			c.release()
+			var res T
+			return res, errs.ErrClientClosed
+		}
+		return c.create(ctx)
+	}
+	// 如果收到连接，假设不会过期
+	return conn.t, nil
+}
+
+// create 在已经占用名额的前提下创建连接，创建失败则归还名额
+func (c *ConnPool[T]) create(ctx context.Context) (T, error) {
+	res, err := c.factory(ctx)
+
+	if err != nil {
+		c.release()
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (c *ConnPool[T]) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// release 释放一个活跃连接的名额
+// 如果有g在等待，则直接把名额转交给对方，由对方创建连接
+func (c *ConnPool[T]) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.handOff(nil) {
+		return
+	}
+
+	c.activeCnt--
+}
+
+// handOff 将连接交给等待队列中的某个g，调用方需要持有锁
+func (c *ConnPool[T]) handOff(conn *Conn[T]) bool {
+	for k, v := range c.waitQ {
+		delete(c.waitQ, k)
+		v <- conn
+		return true
+	}
+	return false
+}
@@ -3,35 +3,79 @@ package rpc
 import (
 	"context"
 	"github.com/uzziahlin/transport/rpc/message"
+	"io"
+	"sync/atomic"
 )
 
+// Proxy 发送请求并返回响应，oneway请求没有响应，成功时返回nil, nil
 type Proxy interface {
 	Invoke(ctx context.Context, req *message.Request) (*message.Response, error)
 }
 
 func NewRemoteProxy(addr string) *RemoteProxy {
+	return newRemoteProxy(addr, defaultTransport.Dial)
+}
+
+func newRemoteProxy(addr string, dial Dialer) *RemoteProxy {
 	return &RemoteProxy{
-		client:      NewRpcClient(addr),
+		addr:        addr,
+		client:      newRpcClient(addr, dial),
 		reqEncoder:  &message.DefaultRequestEncoder{},
 		respEncoder: &message.DefaultResponseEncoder{},
 	}
 }
 
 type RemoteProxy struct {
+	addr        string
 	client      Client
 	reqEncoder  message.RequestEncoder
 	respEncoder message.ResponseEncoder
+	// onClose 关闭时调用，例如注销连接池的指标
+	onClose func()
+	// msgID 请求没有指定id时使用的请求id
+	msgID uint32
+}
+
+// Addr 返回服务端的地址
+func (r *RemoteProxy) Addr() string {
+	return r.addr
+}
+
+// Close 关闭底层客户端持有的连接
+func (r *RemoteProxy) Close() error {
+	if r.onClose != nil {
+		r.onClose()
+	}
+	if c, ok := r.client.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
+// Invoke 请求没有指定id时会分配一个id，id为0的响应会被客户端当作GOAWAY帧
 func (r *RemoteProxy) Invoke(ctx context.Context, req *message.Request) (*message.Response, error) {
+	if req.MessageId == message.GoAwayMessageId {
+		withID := *req
+		withID.MessageId = nextMessageID(&r.msgID)
+		req = &withID
+	}
 
 	encodedReq := r.reqEncoder.Encode(req)
 
 	resp, err := r.client.Send(ctx, encodedReq)
 
-	if err != nil {
+	if err != nil || req.IsOneway() {
 		return nil, err
 	}
 
 	return r.respEncoder.Decode(resp)
 }
+
+// nextMessageID 生成请求id，跳过GOAWAY帧使用的id
+func nextMessageID(counter *uint32) uint32 {
+	id := atomic.AddUint32(counter, 1)
+	if id == message.GoAwayMessageId {
+		id = atomic.AddUint32(counter, 1)
+	}
+	return id
+}
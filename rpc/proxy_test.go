@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/serialize/json"
+)
+
+// TestRemoteProxy_ZeroMessageId 直接使用RemoteProxy时请求没有指定id，响应不能被当作GOAWAY帧
+func TestRemoteProxy_ZeroMessageId(t *testing.T) {
+	endpoint := NewEndPoint("")
+	require.NoError(t, endpoint.Register(&UserServiceImpl{}))
+
+	proxy := newRemoteProxy("pipe", func(ctx context.Context, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go endpoint.handler(server)
+		return client, nil
+	})
+	t.Cleanup(func() {
+		_ = proxy.Close()
+	})
+
+	data, err := (&json.Serializer{}).Serialize(&UserReq{Id: "1"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		service string
+		wantErr bool
+	}{
+		{
+			name:    "ok",
+			service: "user-service",
+		},
+		{
+			name:    "unknown service",
+			service: "unknown-service",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			req := &message.Request{
+				RequestHeader: message.RequestHeader{
+					Header: message.Header{
+						Serializer: (&json.Serializer{}).Code(),
+					},
+					ServiceName: tc.service,
+					MethodName:  "GetById",
+				},
+				Data: data,
+			}
+
+			resp, err := proxy.Invoke(ctx, req)
+			require.NoError(t, err)
+			assert.NotEqual(t, message.GoAwayMessageId, resp.MessageId)
+			// 调用方的请求不会被修改
+			assert.Equal(t, message.GoAwayMessageId, req.MessageId)
+			assert.Equal(t, tc.wantErr, resp.Error != "")
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"context"
+	"github.com/uzziahlin/transport/logger"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/status"
+	"runtime/debug"
+)
+
+// PanicHandler 处理请求时发生panic的回调，recovered为recover()的返回值，stack为发生panic时的调用栈
+type PanicHandler func(ctx context.Context, req *message.Request, recovered any, stack []byte)
+
+// WithPanicHandler 设置处理请求时发生panic的回调，例如上报到告警系统
+// panic可能来自服务方法，也可能来自Authenticator、Policy等处理请求的其他环节，
+// 无论是否设置回调，panic都会被恢复，输出Error级别的日志，并向客户端返回status.Internal错误
+func WithPanicHandler(h PanicHandler) EndPointOpt {
+	return func(e *EndPoint) {
+		e.panicHandler = h
+	}
+}
+
+// recoverPanic 记录panic的日志以及指标，调用回调，返回给客户端的错误中只包含请求id，不暴露panic的内容
+// msg为日志的内容，区分服务方法以及处理请求的其他环节
+func (e *EndPoint) recoverPanic(ctx context.Context, req *message.Request, recovered any, msg string) error {
+	stack := debug.Stack()
+
+	kv := []any{
+		logger.KeyService, req.ServiceName,
+		logger.KeyMethod, req.MethodName,
+		logger.KeyMessageID, req.MessageId,
+	}
+
+	if p, ok := PeerFromContext(ctx); ok && p.Addr != nil {
+		kv = append(kv, logger.KeyPeer, p.Addr.String())
+	}
+
+	e.logger.Error(msg, append(kv, logger.KeyPanic, recovered, logger.KeyStack, string(stack))...)
+
+	if e.metrics != nil {
+		e.metrics.observePanic(e.metricLabels(req))
+	}
+
+	if e.panicHandler != nil {
+		e.panicHandler(ctx, req, recovered, stack)
+	}
+
+	return status.Newf(status.Internal, "micro：服务内部错误, message_id=%d", req.MessageId)
+}
@@ -0,0 +1,124 @@
+package rpc
+
+import (
+	"context"
+	"log"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/logger"
+	"github.com/uzziahlin/transport/rpc/auth"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/metrics"
+	"github.com/uzziahlin/transport/rpc/status"
+)
+
+func TestEndPoint_recoverPanic(t *testing.T) {
+	buf := &syncBuffer{}
+	reg := metrics.NewRegistry()
+
+	type panicInfo struct {
+		msgID     uint32
+		recovered any
+		stack     []byte
+	}
+	panicC := make(chan panicInfo, 1)
+
+	endpoint := NewEndPoint(":8081",
+		WithServerLogger(logger.NewStdLogger(log.New(buf, "", 0), logger.LevelInfo)),
+		WithServerMetrics(reg),
+		WithPanicHandler(func(ctx context.Context, req *message.Request, recovered any, stack []byte) {
+			panicC <- panicInfo{msgID: req.MessageId, recovered: recovered, stack: stack}
+		}))
+	endpoint.Register(&panicService{})
+
+	var dials int32
+	constructor := NewProxyConstructor(WithDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		client, server := net.Pipe()
+		go endpoint.handler(server)
+		return client, nil
+	}))
+	t.Cleanup(func() {
+		_ = constructor.Close()
+	})
+
+	userService := &UserService{}
+	require.NoError(t, constructor.InitProxy(userService))
+
+	_, err := userService.GetById(context.Background(), &UserReq{Id: "panic"})
+	assert.Equal(t, status.New(status.Internal, "micro：服务内部错误, message_id=1"), err)
+
+	info := <-panicC
+	assert.Equal(t, uint32(1), info.msgID)
+	assert.Equal(t, "boom", info.recovered)
+	assert.Contains(t, string(info.stack), "panicService")
+
+	// 连接仍然可以继续使用
+	resp, err := userService.GetById(context.Background(), &UserReq{Id: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, "response: 1", resp.Content)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dials))
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "[ERROR] 服务方法发生panic service=user-service method=GetById message_id=1 peer=pipe panic=boom stack="), out)
+
+	var sb strings.Builder
+	require.NoError(t, reg.WritePrometheus(&sb))
+	assert.Contains(t, sb.String(), `rpc_server_panics_total{service="user-service",method="GetById"} 1`)
+}
+
+// TestEndPoint_recoverAuthenticatorPanic Authenticator发生panic时同样返回status.Internal，进程不会退出
+func TestEndPoint_recoverAuthenticatorPanic(t *testing.T) {
+	buf := &syncBuffer{}
+	panicC := make(chan any, 2)
+
+	endpoint := NewEndPoint(":8081",
+		WithServerLogger(logger.NewStdLogger(log.New(buf, "", 0), logger.LevelInfo)),
+		WithAuthenticator(auth.AuthenticatorFunc(func(ctx context.Context, req *message.Request) (*auth.Principal, error) {
+			panic("auth boom")
+		})),
+		WithPanicHandler(func(ctx context.Context, req *message.Request, recovered any, stack []byte) {
+			panicC <- recovered
+		}))
+	require.NoError(t, endpoint.Register(&UserServiceImpl{}))
+
+	constructor := NewProxyConstructor(WithDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go endpoint.handler(server)
+		return client, nil
+	}))
+	t.Cleanup(func() {
+		_ = constructor.Close()
+	})
+
+	userService := &UserService{}
+	require.NoError(t, constructor.InitProxy(userService))
+
+	for i := 1; i <= 2; i++ {
+		_, err := userService.GetById(context.Background(), &UserReq{Id: "1"})
+		assert.Equal(t, status.Newf(status.Internal, "micro：服务内部错误, message_id=%d", i), err)
+		assert.Equal(t, "auth boom", <-panicC)
+	}
+
+	assert.True(t, strings.HasPrefix(buf.String(), "[ERROR] 处理请求时发生panic service=user-service method=GetById message_id=1"), buf.String())
+}
+
+type panicService struct{}
+
+func (p *panicService) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
+	if req.Id == "panic" {
+		panic("boom")
+	}
+	return &UserResp{Content: "response: " + req.Id}, nil
+}
+
+func (p *panicService) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "user-service",
+	}
+}
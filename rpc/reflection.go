@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/uzziahlin/transport/rpc/status"
+	"reflect"
+)
+
+// ReflectionServiceName 内置反射服务的服务名
+const ReflectionServiceName = "rpc.reflection"
+
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// WithReflection 注册内置的反射服务，客户端可以通过ReflectionClient查询服务端注册的服务以及方法
+// 反射服务会暴露服务的类型信息，默认不开启
+func WithReflection() EndPointOpt {
+	return func(e *EndPoint) {
+		e.Register(&reflectionService{ep: e})
+	}
+}
+
+// ServiceDescriptor 服务的描述信息
+type ServiceDescriptor struct {
+	Name    string              `json:"name"`
+	Version string              `json:"version,omitempty"`
+	Group   string              `json:"group,omitempty"`
+	Methods []*MethodDescriptor `json:"methods"`
+}
+
+// MethodDescriptor 方法的描述信息，类型名包含包路径
+// 请求以及响应不是proto.Message时，Request以及Response为使用json序列化协议时的报文结构
+type MethodDescriptor struct {
+	Name         string  `json:"name"`
+	RequestType  string  `json:"request_type"`
+	ResponseType string  `json:"response_type"`
+	Request      *Schema `json:"request,omitempty"`
+	Response     *Schema `json:"response,omitempty"`
+}
+
+type ListServicesReq struct{}
+
+type ListServicesResp struct {
+	Services []string `json:"services"`
+}
+
+type DescribeServiceReq struct {
+	Service string `json:"service"`
+	// Version 版本范围，为空时返回分组中最高版本的实例
+	Version string `json:"version,omitempty"`
+	Group   string `json:"group,omitempty"`
+}
+
+type DescribeServiceResp struct {
+	Service *ServiceDescriptor `json:"service"`
+}
+
+// ReflectionClient 反射服务的客户端，通过ProxyConstructor.InitProxy初始化
+type ReflectionClient struct {
+	// Addr 服务端地址
+	Addr string
+
+	// ListServices 返回所有已经注册的服务名，按照字典序排序
+	ListServices func(ctx context.Context, req *ListServicesReq) (*ListServicesResp, error) `rpc:"idempotent,serializer=json"`
+
+	// DescribeService 返回服务的所有方法，服务不存在或者没有匹配的实例时返回status.NotFound
+	DescribeService func(ctx context.Context, req *DescribeServiceReq) (*DescribeServiceResp, error) `rpc:"idempotent,serializer=json"`
+}
+
+func (c *ReflectionClient) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: ReflectionServiceName,
+		Addr:        c.Addr,
+	}
+}
+
+// reflectionService 内置的反射服务，固定使用json序列化协议描述报文结构
+type reflectionService struct {
+	ep *EndPoint
+}
+
+func (s *reflectionService) ListServices(ctx context.Context, req *ListServicesReq) (*ListServicesResp, error) {
+	return &ListServicesResp{
+		Services: s.ep.serviceNames(),
+	}, nil
+}
+
+func (s *reflectionService) DescribeService(ctx context.Context, req *DescribeServiceReq) (*DescribeServiceResp, error) {
+	stub, err := s.ep.service(req.Service, req.Group, req.Version)
+	if status.CodeOf(err) == status.Unimplemented {
+		return nil, status.Newf(status.NotFound, "micro：服务 %q 不存在", req.Service).
+			WithDetails(s.ep.serviceNames()...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &DescribeServiceResp{
+		Service: stub.describe(),
+	}, nil
+}
+
+func (s *reflectionService) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: ReflectionServiceName,
+	}
+}
+
+// describe 根据注册时解析的方法表生成服务的描述信息，方法按照方法名排序
+func (r *reflectionStub) describe() *ServiceDescriptor {
+	res := &ServiceDescriptor{
+		Name:    r.info.ServiceName,
+		Version: r.info.Version,
+		Group:   r.info.Group,
+		Methods: make([]*MethodDescriptor, 0, len(r.methodNames)),
+	}
+
+	for _, name := range r.methodNames {
+		m := r.methods[name]
+		res.Methods = append(res.Methods, &MethodDescriptor{
+			Name:         name,
+			RequestType:  typeName(m.argType),
+			ResponseType: typeName(m.replyType),
+			Request:      messageSchema(m.argType),
+			Response:     messageSchema(m.replyType),
+		})
+	}
+
+	return res
+}
+
+// messageSchema proto.Message使用proto序列化协议，报文结构由.proto文件描述，不生成Schema
+func messageSchema(typ reflect.Type) *Schema {
+	if typ.Implements(protoMessageType) {
+		return nil
+	}
+	return SchemaOf(typ)
+}
@@ -0,0 +1,128 @@
+package rpc_test
+
+import (
+	"context"
+	"github.com/uzziahlin/transport/rpc"
+	"github.com/uzziahlin/transport/rpc/rpctest"
+	"github.com/uzziahlin/transport/rpc/serialize/proto"
+	"github.com/uzziahlin/transport/rpc/status"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReflection(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("", rpc.WithReflection())
+	endpoint.Register(&rpc.UserServiceImpl{})
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	client := &rpc.ReflectionClient{}
+	require.NoError(t, server.NewConstructor().InitProxy(client))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	services, err := client.ListServices(ctx, &rpc.ListServicesReq{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{rpc.ReflectionServiceName, "user-service"}, services.Services)
+
+	testCases := []struct {
+		name     string
+		service  string
+		want     *rpc.ServiceDescriptor
+		wantCode status.Code
+	}{
+		{
+			name:    "user service",
+			service: "user-service",
+			want: &rpc.ServiceDescriptor{
+				Name: "user-service",
+				Methods: []*rpc.MethodDescriptor{
+					{
+						Name:         "GetById",
+						RequestType:  "github.com/uzziahlin/transport/rpc.UserReq",
+						ResponseType: "github.com/uzziahlin/transport/rpc.UserResp",
+						Request: &rpc.Schema{
+							Type:       "object",
+							Title:      "github.com/uzziahlin/transport/rpc.UserReq",
+							Properties: map[string]*rpc.Schema{"Id": {Type: "string"}},
+						},
+						Response: &rpc.Schema{
+							Type:       "object",
+							Title:      "github.com/uzziahlin/transport/rpc.UserResp",
+							Properties: map[string]*rpc.Schema{"Content": {Type: "string"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "service not found",
+			service:  "order-service",
+			wantCode: status.NotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.DescribeService(ctx, &rpc.DescribeServiceReq{Service: tc.service})
+			if tc.wantCode != status.OK {
+				assert.Equal(t, tc.wantCode, status.CodeOf(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, resp.Service)
+		})
+	}
+}
+
+func TestReflection_Proto(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("", rpc.WithReflection())
+	endpoint.Register(&rpc.UserServiceProto{})
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	client := &rpc.ReflectionClient{}
+	require.NoError(t, server.NewConstructor().InitProxy(client))
+
+	resp, err := client.DescribeService(context.Background(), &rpc.DescribeServiceReq{Service: "user-service"})
+	require.NoError(t, err)
+
+	// proto.Message的报文结构由.proto文件描述，只返回类型名
+	require.Len(t, resp.Service.Methods, 1)
+	method := resp.Service.Methods[0]
+	assert.Equal(t, "github.com/uzziahlin/transport/rpc/proto/user_service/gen.UserReq", method.RequestType)
+	assert.Nil(t, method.Request)
+	assert.Nil(t, method.Response)
+}
+
+func TestReflection_ProtoSerializer(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("", rpc.WithReflection())
+	endpoint.Register(&rpc.UserServiceImpl{})
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	// 反射服务固定使用json，不受代理构造器默认序列化协议的影响
+	client := &rpc.ReflectionClient{}
+	require.NoError(t, server.NewConstructor(rpc.WithSerializer(&proto.Serializer{})).InitProxy(client))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	list, err := client.ListServices(ctx, &rpc.ListServicesReq{})
+	require.NoError(t, err)
+	assert.Contains(t, list.Services, "user-service")
+
+	resp, err := client.DescribeService(ctx, &rpc.DescribeServiceReq{Service: "user-service"})
+	require.NoError(t, err)
+	assert.Equal(t, "user-service", resp.Service.Name)
+}
@@ -0,0 +1,176 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/uzziahlin/transport/logger"
+	"github.com/uzziahlin/transport/rpc/message"
+)
+
+// RegistryEventType 服务表变化的类型
+type RegistryEventType int
+
+const (
+	ServiceRegistered RegistryEventType = iota + 1
+	ServiceUnregistered
+)
+
+func (t RegistryEventType) String() string {
+	switch t {
+	case ServiceRegistered:
+		return "registered"
+	case ServiceUnregistered:
+		return "unregistered"
+	default:
+		return fmt.Sprintf("RegistryEventType(%d)", int(t))
+	}
+}
+
+// RegistryEvent 服务注册或者注销的事件，服务发现可以据此发布或者撤回服务
+type RegistryEvent struct {
+	Type    RegistryEventType
+	Service ServiceInfo
+}
+
+// RegistryListener 监听服务表的变化，事件按照Register以及Unregister的顺序同步回调
+// 回调中不能再调用Register或者Unregister
+type RegistryListener func(ev RegistryEvent)
+
+// WithRegistryListener 添加服务表变化的监听器，在该选项之前通过其他选项注册的内置服务不会产生事件
+func WithRegistryListener(l RegistryListener) EndPointOpt {
+	return func(e *EndPoint) {
+		e.registryListeners = append(e.registryListeners, l)
+	}
+}
+
+// Register 注册服务，服务名、分组以及版本号都相同时替换原有的服务，可以在EndPoint运行时调用
+// 原有服务正在处理的请求不受影响，之后的请求由新的服务处理。版本号不是合法的语义化版本或者方法选项不合法时返回error，服务不会被注册
+// 签名不符合要求的导出方法不能被远程调用，注册时会输出警告日志
+func (e *EndPoint) Register(service Service) error {
+	stub, err := newServiceStub(service)
+	if err != nil {
+		return err
+	}
+	stub.serializers = e.serializers
+	stub.compressors = e.compressors
+
+	name := stub.info.ServiceName
+
+	for _, m := range stub.rejected {
+		e.logger.Warn("方法签名不符合要求，不能被远程调用", logger.KeyService, name, logger.KeyMethod, m.name, "reason", m.reason)
+	}
+
+	e.registryMu.Lock()
+	defer e.registryMu.Unlock()
+
+	e.servicesMu.Lock()
+	stubs := e.services[name]
+	replaced := false
+	for i, s := range stubs {
+		if s.sameVariant(stub) {
+			stubs[i] = stub
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		e.services[name] = append(stubs, stub)
+	}
+	e.servicesMu.Unlock()
+
+	e.health.set(name, HealthServing)
+
+	e.emit(RegistryEvent{Type: ServiceRegistered, Service: stub.info})
+
+	return nil
+}
+
+// Unregister 注销服务名、分组以及版本号与info相同的服务，之后的请求不再由该服务处理，并等待正在处理的请求结束
+// ctx结束时不再等待，返回ctx.Err()，正在处理的请求仍然会继续执行
+func (e *EndPoint) Unregister(ctx context.Context, info ServiceInfo) error {
+	target, err := newServiceStub(&staticService{info: info})
+	if err != nil {
+		return err
+	}
+
+	e.registryMu.Lock()
+
+	e.servicesMu.Lock()
+	var stub *reflectionStub
+	stubs := e.services[info.ServiceName]
+	for i, s := range stubs {
+		if s.sameVariant(target) {
+			stub = s
+			stubs = append(stubs[:i:i], stubs[i+1:]...)
+			break
+		}
+	}
+	if len(stubs) == 0 {
+		delete(e.services, info.ServiceName)
+	} else {
+		e.services[info.ServiceName] = stubs
+	}
+	e.servicesMu.Unlock()
+
+	if stub == nil {
+		e.registryMu.Unlock()
+		return fmt.Errorf("micro：服务 %q 的实例 %s 没有注册", info.ServiceName, variantName(info.Group, info.Version))
+	}
+
+	// 所有实例都注销之后，健康检查的结果为服务不存在
+	if len(stubs) == 0 {
+		e.health.remove(info.ServiceName)
+	}
+
+	// 先发布事件，让服务发现尽快撤回服务，再等待正在处理的请求
+	e.emit(RegistryEvent{Type: ServiceUnregistered, Service: stub.info})
+
+	e.registryMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		stub.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *EndPoint) emit(ev RegistryEvent) {
+	for _, l := range e.registryListeners {
+		l(ev)
+	}
+}
+
+// service 查找与分组以及版本范围匹配的服务
+func (e *EndPoint) service(name, group, version string) (*reflectionStub, error) {
+	e.servicesMu.RLock()
+	defer e.servicesMu.RUnlock()
+	return e.resolveLocked(name, group, version)
+}
+
+// acquireService 查找请求对应的服务并记录一个正在处理的请求，调用方处理结束后需要调用inflight.Done
+// 在持有锁时增加计数，保证Unregister删除服务之后等待的请求不会遗漏
+func (e *EndPoint) acquireService(req *message.Request) (*reflectionStub, error) {
+	e.servicesMu.RLock()
+	defer e.servicesMu.RUnlock()
+	stub, err := e.resolveLocked(req.ServiceName, req.Meta[groupKey], req.Meta[versionKey])
+	if err == nil {
+		stub.inflight.Add(1)
+	}
+	return stub, err
+}
+
+// staticService 只有服务信息的Service，用于按照服务信息查找已经注册的服务
+type staticService struct {
+	info ServiceInfo
+}
+
+func (s *staticService) Info() ServiceInfo {
+	return s.info
+}
@@ -0,0 +1,151 @@
+package rpc_test
+
+import (
+	"context"
+	"github.com/uzziahlin/transport/rpc"
+	"github.com/uzziahlin/transport/rpc/rpctest"
+	"github.com/uzziahlin/transport/rpc/status"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndPoint_Unregister(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		events []rpc.RegistryEvent
+	)
+
+	endpoint := rpc.NewEndPoint("", rpc.WithRegistryListener(func(ev rpc.RegistryEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, ev)
+	}))
+
+	service := &rpc.UserServiceBlocking{
+		Entered: make(chan struct{}, 1),
+		Release: make(chan struct{}),
+	}
+	endpoint.Register(service)
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	userService := &rpc.UserService{}
+	require.NoError(t, server.NewConstructor().InitProxy(userService))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	inflight := make(chan error, 1)
+	go func() {
+		_, err := userService.GetById(ctx, &rpc.UserReq{Id: "1"})
+		inflight <- err
+	}()
+	<-service.Entered
+
+	unregistered := make(chan error, 1)
+	go func() {
+		unregistered <- endpoint.Unregister(ctx, rpc.ServiceInfo{ServiceName: "user-service"})
+	}()
+
+	// 注销之后新的请求不再由该服务处理，但是正在处理的请求可以正常完成
+	require.Eventually(t, func() bool {
+		return endpoint.ServingStatus("user-service") == rpc.HealthServiceUnknown
+	}, time.Second, time.Millisecond)
+
+	_, err := userService.GetById(ctx, &rpc.UserReq{Id: "2"})
+	assert.Equal(t, status.Unimplemented, status.CodeOf(err))
+
+	select {
+	case <-unregistered:
+		t.Fatal("Unregister没有等待正在处理的请求")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(service.Release)
+	assert.NoError(t, <-inflight)
+	assert.NoError(t, <-unregistered)
+
+	assert.Error(t, endpoint.Unregister(ctx, rpc.ServiceInfo{ServiceName: "user-service"}))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []rpc.RegistryEvent{
+		{Type: rpc.ServiceRegistered, Service: rpc.ServiceInfo{ServiceName: "user-service"}},
+		{Type: rpc.ServiceUnregistered, Service: rpc.ServiceInfo{ServiceName: "user-service"}},
+	}, events)
+}
+
+func TestEndPoint_UnregisterTimeout(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("")
+	service := &rpc.UserServiceBlocking{
+		Entered: make(chan struct{}, 1),
+		Release: make(chan struct{}),
+	}
+	endpoint.Register(service)
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	userService := &rpc.UserService{}
+	require.NoError(t, server.NewConstructor().InitProxy(userService))
+
+	go func() {
+		_, _ = userService.GetById(context.Background(), &rpc.UserReq{Id: "1"})
+	}()
+	<-service.Entered
+	defer close(service.Release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, endpoint.Unregister(ctx, rpc.ServiceInfo{ServiceName: "user-service"}))
+}
+
+func TestEndPoint_RegisterConcurrently(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("")
+	endpoint.Register(&rpc.UserServiceImpl{})
+
+	server := rpctest.NewInMemoryServer(t, endpoint)
+
+	userService := &rpc.UserService{}
+	require.NoError(t, server.NewConstructor().InitProxy(userService))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			_ = endpoint.Unregister(ctx, rpc.ServiceInfo{ServiceName: "user-service"})
+			endpoint.Register(&rpc.UserServiceImpl{})
+		}
+	}()
+
+	// 调用的结果要么成功，要么是服务不存在
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, err := userService.GetById(ctx, &rpc.UserReq{Id: "1"})
+				if err != nil {
+					assert.Equal(t, status.Unimplemented, status.CodeOf(err))
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -40,7 +40,8 @@ func RpcReader(r io.Reader) ([]byte, error) {
 
 	cur = cur[rpcDataLenBytes:]
 
-	_, err = r.Read(cur)
+	// 使用ReadFull，避免一次Read无法读取完整报文
+	_, err = io.ReadFull(r, cur)
 
 	if err != nil {
 		return nil, err
@@ -52,7 +53,7 @@ func RpcReader(r io.Reader) ([]byte, error) {
 func readUint32(r io.Reader) ([]byte, uint32, error) {
 	lens := make([]byte, 4)
 
-	_, err := r.Read(lens)
+	_, err := io.ReadFull(r, lens)
 
 	if err != nil {
 		return nil, 0, err
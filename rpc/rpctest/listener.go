@@ -0,0 +1,73 @@
+package rpctest
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// Listener 基于net.Pipe的内存listener，配合Dial使用，连接不经过操作系统的网络栈
+// 类似grpc的bufconn，适合在测试中替代真实的tcp监听
+type Listener struct {
+	connC     chan net.Conn
+	closeC    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewListener() *Listener {
+	return &Listener{
+		connC:  make(chan net.Conn),
+		closeC: make(chan struct{}),
+	}
+}
+
+// Accept 等待Dial建立的连接，listener关闭之后返回net.ErrClosed
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.connC:
+		return conn, nil
+	case <-l.closeC:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *Listener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closeC)
+	})
+	return nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// Dial 建立一个到当前listener的内存连接，addr会被忽略，签名与rpc.Dialer一致
+// 在服务端Accept之前会一直阻塞，直到ctx过期或者listener被关闭
+func (l *Listener) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	select {
+	case l.connC <- server:
+		return client, nil
+	case <-l.closeC:
+	case <-ctx.Done():
+		_ = client.Close()
+		_ = server.Close()
+		return nil, ctx.Err()
+	}
+
+	_ = client.Close()
+	_ = server.Close()
+	return nil, &net.OpError{Op: "dial", Net: "pipe", Addr: pipeAddr{}, Err: net.ErrClosed}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string {
+	return "pipe"
+}
+
+func (pipeAddr) String() string {
+	return "rpctest"
+}
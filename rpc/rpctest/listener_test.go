@@ -0,0 +1,57 @@
+package rpctest
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListener(t *testing.T) {
+	l := NewListener()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	conn, err := l.Dial(context.Background(), "ignored")
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+	require.NoError(t, conn.Close())
+
+	require.NoError(t, l.Close())
+
+	_, err = l.Accept()
+	assert.ErrorIs(t, err, net.ErrClosed)
+
+	_, err = l.Dial(context.Background(), "ignored")
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
+
+func TestListener_DialTimeout(t *testing.T) {
+	l := NewListener()
+	defer l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// 没有g在Accept，Dial会一直阻塞到ctx过期
+	_, err := l.Dial(ctx, "ignored")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,100 @@
+package rpctest
+
+import (
+	"context"
+	"errors"
+	"github.com/uzziahlin/transport/rpc"
+	"github.com/uzziahlin/transport/rpc/errs"
+	"net"
+	"sync"
+	"testing"
+)
+
+// Server 在测试中启动的EndPoint，测试结束时自动关闭
+type Server struct {
+	// Addr 服务端实际监听的地址，内存模式下为"rpctest"
+	Addr     string
+	EndPoint *rpc.EndPoint
+
+	tb        testing.TB
+	dial      rpc.Dialer
+	done      chan struct{}
+	err       error
+	closeOnce sync.Once
+}
+
+// NewServer 在127.0.0.1的随机端口上启动ep，返回时已经可以接受连接
+func NewServer(tb testing.TB, ep *rpc.EndPoint) *Server {
+	tb.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("rpctest: 监听随机端口失败, %v", err)
+	}
+
+	addr := l.Addr().String()
+
+	return start(tb, ep, l, func(ctx context.Context, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", addr)
+	})
+}
+
+// NewInMemoryServer 在内存listener上启动ep，客户端需要通过Dialer建立连接
+func NewInMemoryServer(tb testing.TB, ep *rpc.EndPoint) *Server {
+	tb.Helper()
+
+	l := NewListener()
+
+	return start(tb, ep, l, l.Dial)
+}
+
+func start(tb testing.TB, ep *rpc.EndPoint, l net.Listener, dial rpc.Dialer) *Server {
+	s := &Server{
+		Addr:     l.Addr().String(),
+		EndPoint: ep,
+		tb:       tb,
+		dial:     dial,
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+		s.err = ep.Serve(l)
+	}()
+
+	tb.Cleanup(s.Close)
+
+	return s
+}
+
+// Dialer 返回连接到当前Server的Dialer，调用方传入的地址会被忽略，
+// 所以服务的ServiceInfo.Addr不需要与Server的地址一致
+func (s *Server) Dialer() rpc.Dialer {
+	return s.dial
+}
+
+// NewConstructor 创建使用当前Server的Dialer的ProxyConstructor，测试结束时自动关闭
+func (s *Server) NewConstructor(opts ...rpc.ConstructorOpt) *rpc.ProxyConstructor {
+	opts = append([]rpc.ConstructorOpt{rpc.WithDialer(s.dial)}, opts...)
+
+	c := rpc.NewProxyConstructor(opts...)
+
+	s.tb.Cleanup(func() {
+		_ = c.Close()
+	})
+
+	return c
+}
+
+// Close 关闭EndPoint并等待监听的g退出，可以重复调用
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		_ = s.EndPoint.Close()
+		<-s.done
+
+		if s.err != nil && !errors.Is(s.err, errs.ErrServerClosed) {
+			s.tb.Errorf("rpctest: 服务端异常退出, %v", s.err)
+		}
+	})
+}
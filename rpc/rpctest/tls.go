@@ -0,0 +1,131 @@
+package rpctest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Certs 测试用的证书，所有证书由同一个CA签发，文件位于测试的临时目录
+type Certs struct {
+	CAFile string
+	CAPool *x509.CertPool
+	// ServerCert ServerKey CN为server，对localhost以及127.0.0.1有效
+	ServerCert string
+	ServerKey  string
+	// ClientCert ClientKey CN为client，用于双向TLS
+	ClientCert string
+	ClientKey  string
+
+	dir    string
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	serial int64
+}
+
+// NewCerts 生成CA以及服务端、客户端证书
+func NewCerts(tb testing.TB) *Certs {
+	tb.Helper()
+
+	key := newKey(tb)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "rpctest ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		tb.Fatalf("rpctest: 生成CA证书失败, %v", err)
+	}
+
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		tb.Fatalf("rpctest: 解析CA证书失败, %v", err)
+	}
+
+	dir := tb.TempDir()
+
+	c := &Certs{
+		CAFile:     filepath.Join(dir, "ca.pem"),
+		CAPool:     x509.NewCertPool(),
+		ServerCert: filepath.Join(dir, "server.pem"),
+		ServerKey:  filepath.Join(dir, "server-key.pem"),
+		ClientCert: filepath.Join(dir, "client.pem"),
+		ClientKey:  filepath.Join(dir, "client-key.pem"),
+		dir:        dir,
+		ca:         ca,
+		caKey:      key,
+		serial:     1,
+	}
+
+	c.CAPool.AddCert(ca)
+	writePEM(tb, c.CAFile, "CERTIFICATE", der)
+
+	c.WriteCert(tb, "server", c.ServerCert, c.ServerKey)
+	c.WriteCert(tb, "client", c.ClientCert, c.ClientKey)
+
+	return c
+}
+
+// WriteCert 签发一个CN为cn的证书，写入certFile以及keyFile，已经存在的文件会被覆盖
+// 证书对localhost以及127.0.0.1有效，可以同时用于服务端以及客户端
+func (c *Certs) WriteCert(tb testing.TB, cn, certFile, keyFile string) {
+	tb.Helper()
+
+	key := newKey(tb)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(atomic.AddInt64(&c.serial, 1)),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, c.ca, &key.PublicKey, c.caKey)
+	if err != nil {
+		tb.Fatalf("rpctest: 签发证书失败, %v", err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		tb.Fatalf("rpctest: 编码私钥失败, %v", err)
+	}
+
+	writePEM(tb, certFile, "CERTIFICATE", der)
+	writePEM(tb, keyFile, "EC PRIVATE KEY", keyDer)
+}
+
+func newKey(tb testing.TB) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatalf("rpctest: 生成私钥失败, %v", err)
+	}
+	return key
+}
+
+func writePEM(tb testing.TB, file, typ string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		tb.Fatalf("rpctest: 写入文件 %s 失败, %v", file, err)
+	}
+}
@@ -0,0 +1,149 @@
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema 类似JSON Schema的类型描述，描述的是使用json序列化协议时的报文结构
+// 字段名遵循encoding/json的规则，包括json tag的重命名、忽略以及匿名字段的展开
+type Schema struct {
+	// Type 为object、array、string、integer、number、boolean之一，为空代表任意类型
+	Type   string `json:"type,omitempty"`
+	Format string `json:"format,omitempty"`
+	// Title 命名结构体的类型名，包含包路径
+	Title string `json:"title,omitempty"`
+	// Ref 递归引用的结构体的类型名，与外层某个Schema的Title相同
+	Ref                  string             `json:"$ref,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// SchemaOf 通过反射生成类型的Schema，指针类型使用其指向的类型
+func SchemaOf(typ reflect.Type) *Schema {
+	return schemaOf(typ, make(map[reflect.Type]bool, 4))
+}
+
+// schemaOf visiting记录了当前路径上正在展开的结构体，再次遇到时使用Ref引用，避免无限递归
+func schemaOf(typ reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch {
+	case typ == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case implements(typ, jsonMarshalerType):
+		// 自定义了序列化方式，无法得知报文结构
+		return &Schema{Title: typeName(typ)}
+	case implements(typ, textMarshalerType):
+		return &Schema{Type: "string", Title: typeName(typ)}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: typ.Kind().String()}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: typ.Kind().String()}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		// encoding/json将[]byte编码为base64字符串
+		if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(typ.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(typ.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[typ] {
+			return &Schema{Ref: typeName(typ)}
+		}
+		visiting[typ] = true
+		defer delete(visiting, typ)
+
+		s := &Schema{
+			Type:       "object",
+			Title:      typeName(typ),
+			Properties: make(map[string]*Schema, typ.NumField()),
+		}
+		structFields(typ, visiting, s.Properties)
+		return s
+	default:
+		// interface以及其他无法确定结构的类型
+		return &Schema{}
+	}
+}
+
+// structFields 按照encoding/json的规则收集结构体的字段，外层的字段优先于匿名字段展开的字段
+func structFields(typ reflect.Type, visiting map[reflect.Type]bool, props map[string]*Schema) {
+	var embedded []reflect.Type
+
+	for i := 0; i < typ.NumField(); i++ {
+		fd := typ.Field(i)
+
+		name, _, _ := strings.Cut(fd.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		ft := fd.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		// 没有通过tag重命名的匿名结构体，字段会被展开到外层
+		if fd.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, ft)
+			continue
+		}
+
+		if !fd.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = fd.Name
+		}
+
+		props[name] = schemaOf(fd.Type, visiting)
+	}
+
+	for _, ft := range embedded {
+		inner := make(map[string]*Schema, ft.NumField())
+		structFields(ft, visiting, inner)
+		for name, s := range inner {
+			if _, ok := props[name]; !ok {
+				props[name] = s
+			}
+		}
+	}
+}
+
+func implements(typ reflect.Type, iface reflect.Type) bool {
+	return typ.Implements(iface) || reflect.PointerTo(typ).Implements(iface)
+}
+
+// typeName 返回包含包路径的类型名，未命名的类型返回其字面量
+func typeName(typ reflect.Type) string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Name() == "" || typ.PkgPath() == "" {
+		return typ.String()
+	}
+	return typ.PkgPath() + "." + typ.Name()
+}
@@ -0,0 +1,125 @@
+package rpc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaBase struct {
+	Id      int64
+	Created time.Time `json:"created"`
+}
+
+type schemaNode struct {
+	Value    string        `json:"value"`
+	Children []*schemaNode `json:"children,omitempty"`
+}
+
+type schemaRaw struct{}
+
+func (schemaRaw) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+type schemaUser struct {
+	schemaBase
+	Name    string            `json:"name"`
+	Id      string            `json:"id"`
+	Tags    []string          `json:"tags"`
+	Attrs   map[string]any    `json:"attrs"`
+	Avatar  []byte            `json:"avatar"`
+	Score   *float64          `json:"score,omitempty"`
+	Raw     schemaRaw         `json:"raw"`
+	Ignored string            `json:"-"`
+	Labels  map[string]string `json:",omitempty"`
+	secret  string
+}
+
+func TestSchemaOf(t *testing.T) {
+	testCases := []struct {
+		name string
+		typ  reflect.Type
+		want *Schema
+	}{
+		{
+			name: "basic",
+			typ:  reflect.TypeOf(0),
+			want: &Schema{Type: "integer", Format: "int"},
+		},
+		{
+			name: "pointer",
+			typ:  reflect.TypeOf(new(bool)),
+			want: &Schema{Type: "boolean"},
+		},
+		{
+			name: "struct",
+			typ:  reflect.TypeOf(&UserReq{}),
+			want: &Schema{
+				Type:  "object",
+				Title: "github.com/uzziahlin/transport/rpc.UserReq",
+				Properties: map[string]*Schema{
+					"Id": {Type: "string"},
+				},
+			},
+		},
+		{
+			// 匿名字段展开到外层，外层的同名字段优先
+			name: "json tags and embedded",
+			typ:  reflect.TypeOf(schemaUser{}),
+			want: &Schema{
+				Type:  "object",
+				Title: "github.com/uzziahlin/transport/rpc.schemaUser",
+				Properties: map[string]*Schema{
+					"created": {Type: "string", Format: "date-time"},
+					"name":    {Type: "string"},
+					"id":      {Type: "string"},
+					"Id":      {Type: "integer", Format: "int64"},
+					"tags":    {Type: "array", Items: &Schema{Type: "string"}},
+					"attrs":   {Type: "object", AdditionalProperties: &Schema{}},
+					"avatar":  {Type: "string", Format: "byte"},
+					"score":   {Type: "number", Format: "float64"},
+					"raw":     {Title: "github.com/uzziahlin/transport/rpc.schemaRaw"},
+					"Labels":  {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+				},
+			},
+		},
+		{
+			name: "recursive",
+			typ:  reflect.TypeOf(schemaNode{}),
+			want: &Schema{
+				Type:  "object",
+				Title: "github.com/uzziahlin/transport/rpc.schemaNode",
+				Properties: map[string]*Schema{
+					"value": {Type: "string"},
+					"children": {
+						Type:  "array",
+						Items: &Schema{Ref: "github.com/uzziahlin/transport/rpc.schemaNode"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, SchemaOf(tc.typ))
+		})
+	}
+}
+
+func TestSchemaOf_JSON(t *testing.T) {
+	data, err := json.Marshal(SchemaOf(reflect.TypeOf(schemaNode{})))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"title": "github.com/uzziahlin/transport/rpc.schemaNode",
+		"properties": {
+			"value": {"type": "string"},
+			"children": {"type": "array", "items": {"$ref": "github.com/uzziahlin/transport/rpc.schemaNode"}}
+		}
+	}`, string(data))
+}
@@ -0,0 +1,230 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Range 版本范围，语法与npm保持一致的子集：
+//
+//	""、*              任意版本
+//	1.2.3、=1.2.3      指定版本
+//	>1.2.3 >=1.2 <2    比较，空格分隔的多个条件需要同时满足
+//	^1.2.3             >=1.2.3 <2.0.0，0.x版本只允许修改patch，例如^0.2.3为>=0.2.3 <0.3.0
+//	~1.2.3             >=1.2.3 <1.3.0
+//	1.x、1.2.x、1、1.2 省略或者使用x、*的部分可以是任意值
+//	a || b             满足其中一个范围即可
+type Range struct {
+	raw  string
+	sets [][]comparator
+}
+
+type comparator struct {
+	op string
+	v  Version
+}
+
+func (c comparator) match(v Version) bool {
+	r := v.Compare(c.v)
+	switch c.op {
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	default:
+		return r == 0
+	}
+}
+
+// ParseRange 解析版本范围
+func ParseRange(r string) (Range, error) {
+	res := Range{raw: r}
+
+	for _, set := range strings.Split(r, "||") {
+		var comps []comparator
+		for _, f := range strings.Fields(set) {
+			cs, err := parseComparator(f)
+			if err != nil {
+				return Range{}, fmt.Errorf("semver: 版本范围 %q 不合法, %w", r, err)
+			}
+			comps = append(comps, cs...)
+		}
+		res.sets = append(res.sets, comps)
+	}
+
+	return res, nil
+}
+
+// MustParseRange 与ParseRange相同，解析失败时panic
+func MustParseRange(r string) Range {
+	res, err := ParseRange(r)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Match 判断版本是否在范围内
+func (r Range) Match(v Version) bool {
+	// 零值代表任意版本
+	if len(r.sets) == 0 {
+		return true
+	}
+
+	for _, set := range r.sets {
+		ok := true
+		for _, c := range set {
+			if !c.match(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAny 范围是否匹配任意版本
+func (r Range) IsAny() bool {
+	for _, set := range r.sets {
+		if len(set) == 0 {
+			return true
+		}
+	}
+	return len(r.sets) == 0
+}
+
+func (r Range) String() string {
+	return r.raw
+}
+
+// partial 可以省略minor以及patch的版本，n为明确指定的部分的数量
+type partial struct {
+	v Version
+	n int
+}
+
+// next 将最后一个明确指定的部分加一，得到范围的上界，例如1.2的上界为1.3.0
+func (p partial) next() Version {
+	switch p.n {
+	case 1:
+		return Version{Major: p.v.Major + 1}
+	case 2:
+		return Version{Major: p.v.Major, Minor: p.v.Minor + 1}
+	default:
+		return Version{Major: p.v.Major, Minor: p.v.Minor, Patch: p.v.Patch + 1}
+	}
+}
+
+func parsePartial(s string) (partial, error) {
+	s = strings.TrimPrefix(s, "v")
+
+	var res partial
+
+	if s == "" {
+		return res, fmt.Errorf("缺少版本号")
+	}
+
+	main, _, hasPre := strings.Cut(s, "-")
+
+	parts := strings.Split(main, ".")
+	if len(parts) > 3 {
+		return res, fmt.Errorf("版本 %q 不合法", s)
+	}
+
+	nums := [3]*uint64{&res.v.Major, &res.v.Minor, &res.v.Patch}
+	for i, p := range parts {
+		if p == "x" || p == "X" || p == "*" {
+			break
+		}
+		n, err := parseNumber(p)
+		if err != nil {
+			return res, err
+		}
+		*nums[i] = n
+		res.n++
+	}
+
+	if hasPre {
+		if res.n != 3 {
+			return res, fmt.Errorf("版本 %q 不完整时不能有预发布标识", s)
+		}
+		v, err := Parse(s)
+		if err != nil {
+			return res, err
+		}
+		res.v = v
+	}
+
+	return res, nil
+}
+
+func parseComparator(s string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(s, prefix) {
+			op = prefix
+			s = s[len(prefix):]
+			break
+		}
+	}
+
+	p, err := parsePartial(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// 任意版本
+	if p.n == 0 {
+		if op == "<" || op == ">" {
+			return []comparator{{op: "<", v: Version{}}}, nil
+		}
+		return nil, nil
+	}
+
+	switch op {
+	case ">":
+		if p.n < 3 {
+			return []comparator{{op: ">=", v: p.next()}}, nil
+		}
+		return []comparator{{op: ">", v: p.v}}, nil
+	case ">=":
+		return []comparator{{op: ">=", v: p.v}}, nil
+	case "<":
+		return []comparator{{op: "<", v: p.v}}, nil
+	case "<=":
+		if p.n < 3 {
+			return []comparator{{op: "<", v: p.next()}}, nil
+		}
+		return []comparator{{op: "<=", v: p.v}}, nil
+	case "^":
+		upper := Version{Major: p.v.Major + 1}
+		switch {
+		case p.v.Major == 0 && p.n >= 2 && p.v.Minor != 0:
+			upper = Version{Minor: p.v.Minor + 1}
+		case p.v.Major == 0 && p.n == 3:
+			upper = Version{Patch: p.v.Patch + 1}
+		case p.v.Major == 0 && p.n == 2:
+			upper = Version{Minor: 1}
+		}
+		return []comparator{{op: ">=", v: p.v}, {op: "<", v: upper}}, nil
+	case "~":
+		upper := Version{Major: p.v.Major, Minor: p.v.Minor + 1}
+		if p.n == 1 {
+			upper = Version{Major: p.v.Major + 1}
+		}
+		return []comparator{{op: ">=", v: p.v}, {op: "<", v: upper}}, nil
+	default:
+		if p.n < 3 {
+			return []comparator{{op: ">=", v: p.v}, {op: "<", v: p.next()}}, nil
+		}
+		return []comparator{{op: "=", v: p.v}}, nil
+	}
+}
@@ -0,0 +1,137 @@
+// Package semver 解析语义化版本以及版本范围，用于按照版本路由服务
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version 语义化版本 major.minor.patch[-prerelease][+build]，比较时忽略build
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+}
+
+// Parse 解析版本号，允许v前缀，例如v1.2.3、1.2.3-beta.1
+func Parse(v string) (Version, error) {
+	var res Version
+
+	s := strings.TrimPrefix(v, "v")
+	s, _, _ = strings.Cut(s, "+")
+
+	s, pre, ok := strings.Cut(s, "-")
+	if ok {
+		if pre == "" {
+			return res, fmt.Errorf("semver: 版本 %q 的预发布标识为空", v)
+		}
+		res.Prerelease = strings.Split(pre, ".")
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return res, fmt.Errorf("semver: 版本 %q 必须为major.minor.patch的格式", v)
+	}
+
+	nums := [3]*uint64{&res.Major, &res.Minor, &res.Patch}
+	for i, p := range parts {
+		n, err := parseNumber(p)
+		if err != nil {
+			return res, fmt.Errorf("semver: 版本 %q 不合法, %w", v, err)
+		}
+		*nums[i] = n
+	}
+
+	return res, nil
+}
+
+// MustParse 与Parse相同，解析失败时panic
+func MustParse(v string) Version {
+	res, err := Parse(v)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func parseNumber(s string) (uint64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("数字不能为空")
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("数字 %q 不能有前导0", s)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	return s
+}
+
+// Compare 比较两个版本，v小于、等于、大于o时分别返回-1、0、1
+// 预发布版本小于对应的正式版本，例如1.0.0-alpha < 1.0.0
+func (v Version) Compare(o Version) int {
+	if c := compareInt(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+func compareInt(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func comparePrerelease(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareInt(uint64(len(a)), uint64(len(b)))
+}
+
+// compareIdentifier 数字标识按照数值比较，且小于非数字标识，非数字标识按照字典序比较
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInt(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
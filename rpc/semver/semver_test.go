@@ -0,0 +1,128 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		v       string
+		want    Version
+		wantErr bool
+	}{
+		{name: "basic", v: "1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{name: "v prefix", v: "v1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{name: "prerelease", v: "1.0.0-beta.1", want: Version{Major: 1, Prerelease: []string{"beta", "1"}}},
+		{name: "build", v: "1.0.0+20230101", want: Version{Major: 1}},
+		{name: "partial", v: "1.2", wantErr: true},
+		{name: "leading zero", v: "01.2.3", wantErr: true},
+		{name: "not a number", v: "1.a.3", wantErr: true},
+		{name: "empty prerelease", v: "1.2.3-", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := Parse(tc.v)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, v)
+		})
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	// 按照从小到大排列
+	versions := []string{
+		"0.9.0",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.10.0",
+		"2.0.0",
+	}
+
+	for i := range versions {
+		for j := range versions {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			assert.Equal(t, want, MustParse(versions[i]).Compare(MustParse(versions[j])), "%s vs %s", versions[i], versions[j])
+		}
+	}
+}
+
+func TestRange_Match(t *testing.T) {
+	testCases := []struct {
+		r        string
+		match    []string
+		mismatch []string
+	}{
+		{r: "", match: []string{"0.0.1", "1.2.3", "10.0.0"}},
+		{r: "*", match: []string{"0.0.1", "1.2.3"}},
+		{r: "1.2.3", match: []string{"1.2.3"}, mismatch: []string{"1.2.4", "1.2.3-beta"}},
+		{r: "=1.2.3", match: []string{"1.2.3"}, mismatch: []string{"1.2.2"}},
+		{r: "1", match: []string{"1.0.0", "1.9.9"}, mismatch: []string{"0.9.9", "2.0.0"}},
+		{r: "1.x", match: []string{"1.0.0", "1.9.9"}, mismatch: []string{"2.0.0"}},
+		{r: "1.2.x", match: []string{"1.2.0", "1.2.9"}, mismatch: []string{"1.3.0", "1.1.9"}},
+		{r: ">1.2.3", match: []string{"1.2.4"}, mismatch: []string{"1.2.3"}},
+		{r: ">1.2", match: []string{"1.3.0"}, mismatch: []string{"1.2.9"}},
+		{r: ">=1.2", match: []string{"1.2.0", "3.0.0"}, mismatch: []string{"1.1.9"}},
+		{r: "<1.2", match: []string{"1.1.9"}, mismatch: []string{"1.2.0"}},
+		{r: "<=1.2", match: []string{"1.2.9"}, mismatch: []string{"1.3.0"}},
+		{r: ">=1.2.0 <2.0.0", match: []string{"1.2.0", "1.9.9"}, mismatch: []string{"2.0.0", "1.1.0"}},
+		{r: "^1.2.3", match: []string{"1.2.3", "1.9.0"}, mismatch: []string{"1.2.2", "2.0.0"}},
+		{r: "^0.2.3", match: []string{"0.2.3", "0.2.9"}, mismatch: []string{"0.3.0"}},
+		{r: "^0.0.3", match: []string{"0.0.3"}, mismatch: []string{"0.0.4"}},
+		{r: "^1.2", match: []string{"1.2.0", "1.9.0"}, mismatch: []string{"2.0.0"}},
+		{r: "~1.2.3", match: []string{"1.2.3", "1.2.9"}, mismatch: []string{"1.3.0"}},
+		{r: "~1", match: []string{"1.0.0", "1.9.0"}, mismatch: []string{"2.0.0"}},
+		{r: "^1 || ^3", match: []string{"1.0.0", "3.1.0"}, mismatch: []string{"2.0.0"}},
+		{r: "v1.2.3", match: []string{"1.2.3"}},
+		{r: ">=1.0.0-beta", match: []string{"1.0.0-beta", "1.0.0-rc.1", "1.0.0"}, mismatch: []string{"1.0.0-alpha"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.r, func(t *testing.T) {
+			r, err := ParseRange(tc.r)
+			require.NoError(t, err)
+			for _, v := range tc.match {
+				assert.True(t, r.Match(MustParse(v)), v)
+			}
+			for _, v := range tc.mismatch {
+				assert.False(t, r.Match(MustParse(v)), v)
+			}
+		})
+	}
+}
+
+func TestParseRange_Invalid(t *testing.T) {
+	for _, r := range []string{">=", "1.2.3.4", "^a", "1.2-beta", ">=01.0.0"} {
+		t.Run(r, func(t *testing.T) {
+			_, err := ParseRange(r)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRange_IsAny(t *testing.T) {
+	assert.True(t, MustParseRange("").IsAny())
+	assert.True(t, MustParseRange("*").IsAny())
+	assert.True(t, Range{}.IsAny())
+	assert.False(t, MustParseRange("^1").IsAny())
+}
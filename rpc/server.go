@@ -1,6 +1,12 @@
 package rpc
 
-import "net"
+import (
+	"context"
+	"crypto/tls"
+	"github.com/uzziahlin/transport/rpc/errs"
+	"net"
+	"sync"
+)
 
 type ConnHandler func(conn net.Conn)
 
@@ -8,32 +14,157 @@ func NewServer(addr string, handler ConnHandler) *Server {
 	server := &Server{
 		addr:    addr,
 		handler: handler,
+		listen:  defaultTransport.Listen,
+		conns:   make(map[net.Conn]struct{}, 16),
 	}
 
 	return server
 }
 
 type Server struct {
-	addr    string
-	handler ConnHandler
+	addr       string
+	handler    ConnHandler
+	listen     func(addr string) (net.Listener, error)
+	mu         sync.Mutex
+	listener   net.Listener
+	conns      map[net.Conn]struct{}
+	closed     bool
+	onShutdown []func()
+	wg         sync.WaitGroup
+	tlsConfig  *tls.Config
 }
 
+// Start 监听addr并开始处理连接，直到Shutdown或者Close被调用，或者监听出错
+// 默认通过NetTransport监听，addr可以是tcp地址或者unix domain socket
 func (s *Server) Start() error {
-	listener, err := net.Listen("tcp", s.addr)
+	listener, err := s.listen(s.addr)
 
 	if err != nil {
 		return err
 	}
 
+	return s.Serve(listener)
+}
+
+// Serve 在给定的listener上处理连接，listener由Server接管，Shutdown或者Close时会被关闭
+// 配置了TLS时，接收的连接都会使用TLS。Shutdown或者Close之后返回errs.ErrServerClosed
+func (s *Server) Serve(listener net.Listener) error {
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = listener.Close()
+		return errs.ErrServerClosed
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
 	for {
 		conn, err := listener.Accept()
 
 		if err != nil {
+			if s.isClosed() {
+				return errs.ErrServerClosed
+			}
 			return err
 		}
 
+		if !s.track(conn) {
+			_ = conn.Close()
+			return errs.ErrServerClosed
+		}
+
 		go func() {
+			defer s.untrack(conn)
 			s.handler(conn)
 		}()
 	}
 }
+
+// RegisterOnShutdown 注册Shutdown时调用的函数，在listener关闭之后调用
+// 协议层可以借此通知客户端，并在处理中的请求结束之后关闭连接
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onShutdown = append(s.onShutdown, f)
+}
+
+// Shutdown 优雅关闭，停止接收新的连接，并等待所有连接的处理函数返回
+// 连接需要由处理函数或者RegisterOnShutdown注册的函数关闭，ctx过期时强制关闭所有连接并返回ctx.Err()
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	listener := s.listener
+	hooks := s.onShutdown
+	s.mu.Unlock()
+
+	if listener != nil {
+		_ = listener.Close()
+	}
+
+	for _, f := range hooks {
+		f()
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		_ = s.Close()
+		return ctx.Err()
+	}
+}
+
+// Close 立即关闭listener以及所有已经建立的连接，正在处理中的请求会因为连接关闭而失败
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+
+	for conn := range s.conns {
+		_ = conn.Close()
+		delete(s.conns, conn)
+	}
+
+	return nil
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	s.wg.Done()
+}
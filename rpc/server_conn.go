@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// goAwayWriteTimeout 写入GOAWAY帧的超时时间，避免客户端不读取数据时阻塞关闭流程
+const goAwayWriteTimeout = 200 * time.Millisecond
+
+// serverConn 服务端连接，记录处理中的请求数量，用于优雅关闭
+// 进入drain状态之后，连接会在没有处理中的请求时关闭
+type serverConn struct {
+	net.Conn
+	sem      chan struct{}
+	writeMu  sync.Mutex
+	mu       sync.Mutex
+	active   int
+	draining bool
+	closed   bool
+}
+
+// newServerConn concurrency为连接同时处理的请求数量上限
+func newServerConn(conn net.Conn, concurrency int) *serverConn {
+	return &serverConn{
+		Conn: conn,
+		sem:  make(chan struct{}, concurrency),
+	}
+}
+
+// acquire 占用一个处理请求的名额，没有名额时阻塞
+func (c *serverConn) acquire() {
+	c.sem <- struct{}{}
+}
+
+// release 归还处理请求的名额
+func (c *serverConn) release() {
+	<-c.sem
+}
+
+// begin 开始处理一个请求，连接已经关闭时返回false，此时请求不会被处理
+func (c *serverConn) begin() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+	c.active++
+	return true
+}
+
+// end 结束处理一个请求，如果连接处于drain状态并且没有处理中的请求，则关闭连接
+func (c *serverConn) end() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.active--
+	if c.draining && c.active == 0 {
+		c.closeLocked()
+	}
+}
+
+// write 写入一个完整的报文，与GOAWAY帧的写入互斥
+func (c *serverConn) write(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := c.Conn.Write(data)
+	return err
+}
+
+// drain 发送GOAWAY帧并进入drain状态，没有处理中的请求时立即关闭连接
+// GOAWAY帧只写入了一部分时报文已经损坏，同样立即关闭连接
+func (c *serverConn) drain(goAway []byte) {
+	c.writeMu.Lock()
+	_ = c.Conn.SetWriteDeadline(time.Now().Add(goAwayWriteTimeout))
+	n, err := c.Conn.Write(goAway)
+	_ = c.Conn.SetWriteDeadline(time.Time{})
+	c.writeMu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.draining = true
+	if c.active == 0 || (err != nil && n > 0) {
+		c.closeLocked()
+	}
+}
+
+func (c *serverConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *serverConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeLocked()
+}
+
+func (c *serverConn) closeLocked() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.Conn.Close()
+}
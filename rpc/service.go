@@ -7,4 +7,9 @@ type Service interface {
 type ServiceInfo struct {
 	ServiceName string
 	Addr        string
+	// Version 服务端为服务的版本号，例如1.2.0；客户端为期望的版本范围，例如^1.2，语法见semver.Range
+	// 客户端为空时匹配任意版本
+	Version string
+	// Group 服务分组，例如按照租户或者环境划分，客户端只会调用相同分组的服务
+	Group string
 }
@@ -0,0 +1,139 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/uzziahlin/transport/rpc/proto/user_service/gen"
+	"time"
+)
+
+type UserService struct {
+	GetById func(ctx context.Context, req *UserReq) (*UserResp, error)
+
+	GetByIdProto func(ctx context.Context, req *gen.UserReq) (*gen.UserResp, error)
+}
+
+type UserReq struct {
+	Id string
+}
+
+type UserResp struct {
+	Content string
+}
+
+func (u UserService) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "user-service",
+		Addr:        "localhost:8081",
+	}
+}
+
+type UserServiceImpl struct {
+}
+
+func (u *UserServiceImpl) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
+	id := req.Id
+
+	return &UserResp{
+		Content: "response: " + id,
+	}, nil
+}
+
+func (u *UserServiceImpl) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "user-service",
+	}
+}
+
+type UserServiceTimeout struct {
+	Msg string
+	Err string
+}
+
+func (u *UserServiceTimeout) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
+	// 模拟耗时的处理，客户端放弃之后服务端也会随之结束
+	select {
+	case <-time.After(3 * time.Second):
+	case <-ctx.Done():
+	}
+	return &UserResp{
+		Content: u.Msg,
+	}, errors.New(u.Err)
+}
+
+func (u *UserServiceTimeout) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "user-service",
+	}
+}
+
+type UserServiceErr struct {
+	Msg string
+	Err string
+}
+
+func (u *UserServiceErr) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
+	return &UserResp{
+		Content: u.Msg,
+	}, errors.New(u.Err)
+}
+
+func (u *UserServiceErr) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "user-service",
+	}
+}
+
+type UserServiceProto struct {
+	Msg string
+	Err string
+}
+
+func (u *UserServiceProto) GetByIdProto(ctx context.Context, req *gen.UserReq) (*gen.UserResp, error) {
+	return &gen.UserResp{
+		Msg: u.Msg,
+	}, errors.New(u.Err)
+}
+
+func (u *UserServiceProto) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "user-service",
+	}
+}
+
+// UserServiceNotify 将收到的请求id发送到Received，并返回Err
+type UserServiceNotify struct {
+	Received chan string
+	Err      error
+}
+
+func (u *UserServiceNotify) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
+	u.Received <- req.Id
+	return &UserResp{}, u.Err
+}
+
+func (u *UserServiceNotify) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "user-service",
+	}
+}
+
+// UserServiceBlocking 进入方法时向Entered发送信号，并阻塞到Release被关闭
+type UserServiceBlocking struct {
+	Entered chan struct{}
+	Release chan struct{}
+}
+
+func (u *UserServiceBlocking) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
+	u.Entered <- struct{}{}
+	<-u.Release
+	return &UserResp{
+		Content: "response: " + req.Id,
+	}, nil
+}
+
+func (u *UserServiceBlocking) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "user-service",
+	}
+}
@@ -0,0 +1,147 @@
+package rpc_test
+
+import (
+	"context"
+	"github.com/uzziahlin/transport/rpc"
+	"github.com/uzziahlin/transport/rpc/rpctest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndPoint_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	service := &rpc.UserServiceBlocking{
+		Entered: make(chan struct{}, 1),
+		Release: make(chan struct{}),
+	}
+
+	endpoint := rpc.NewEndPoint("")
+	endpoint.Register(service)
+
+	server := rpctest.NewServer(t, endpoint)
+
+	userService := &rpc.UserService{}
+	require.NoError(t, server.NewConstructor().InitProxy(userService))
+
+	type result struct {
+		resp *rpc.UserResp
+		err  error
+	}
+
+	resC := make(chan result, 1)
+	go func() {
+		resp, err := userService.GetById(context.Background(), &rpc.UserReq{Id: "in-flight"})
+		resC <- result{resp: resp, err: err}
+	}()
+
+	<-service.Entered
+
+	shutdownC := make(chan error, 1)
+	go func() {
+		shutdownC <- endpoint.Shutdown(context.Background())
+	}()
+
+	// 处理中的请求没有结束之前，Shutdown不会返回
+	select {
+	case err := <-shutdownC:
+		t.Fatalf("Shutdown在请求处理结束之前返回: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(service.Release)
+
+	res := <-resC
+	require.NoError(t, res.err)
+	assert.Equal(t, "response: in-flight", res.resp.Content)
+
+	require.NoError(t, <-shutdownC)
+
+	// 关闭之后不再接收新的连接
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := userService.GetById(ctx, &rpc.UserReq{Id: "after shutdown"})
+	assert.Error(t, err)
+}
+
+func TestEndPoint_ShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	service := &rpc.UserServiceBlocking{
+		Entered: make(chan struct{}, 1),
+		Release: make(chan struct{}),
+	}
+	defer close(service.Release)
+
+	endpoint := rpc.NewEndPoint("")
+	endpoint.Register(service)
+
+	server := rpctest.NewServer(t, endpoint)
+
+	userService := &rpc.UserService{}
+	require.NoError(t, server.NewConstructor().InitProxy(userService))
+
+	errC := make(chan error, 1)
+	go func() {
+		_, err := userService.GetById(context.Background(), &rpc.UserReq{Id: "in-flight"})
+		errC <- err
+	}()
+
+	<-service.Entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// ctx过期之后强制关闭连接，处理中的请求失败
+	assert.Equal(t, context.DeadlineExceeded, endpoint.Shutdown(ctx))
+	assert.Error(t, <-errC)
+}
+
+func TestEndPoint_ShutdownConcurrentAccept(t *testing.T) {
+	t.Parallel()
+
+	endpoint := rpc.NewEndPoint("")
+	endpoint.Register(&rpc.UserServiceImpl{})
+
+	server := rpctest.NewServer(t, endpoint)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 优雅关闭的同时不断建立新的连接，被接受的连接都应该收到GOAWAY并被关闭
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				conn, err := server.Dialer()(ctx, server.Addr)
+				if err != nil {
+					continue
+				}
+				defer func() {
+					_ = conn.Close()
+				}()
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	assert.NoError(t, endpoint.Shutdown(ctx))
+	assert.Less(t, time.Since(start), 3*time.Second)
+
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,61 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/status"
+	"strconv"
+)
+
+// 错误码以及详细信息通过响应的元数据传递
+const (
+	statusCodeKey    = "sys_status"
+	statusDetailsKey = "sys_status_details"
+)
+
+// setResponseError 将错误写入响应，*status.Status的错误码以及详细信息写入响应的元数据
+func setResponseError(resp *message.Response, err error) {
+	resp.Error = err.Error()
+
+	s, ok := status.FromError(err)
+
+	if !ok {
+		return
+	}
+
+	if resp.Meta == nil {
+		resp.Meta = make(map[string]string, 2)
+	}
+
+	resp.Meta[statusCodeKey] = strconv.FormatUint(uint64(s.Code), 10)
+
+	if len(s.Details) > 0 {
+		// json编码之后不会包含元数据的分隔符
+		details, _ := json.Marshal(s.Details)
+		resp.Meta[statusDetailsKey] = string(details)
+	}
+}
+
+// responseError 还原响应中的错误，带有错误码的错误还原为*status.Status
+func responseError(resp *message.Response) error {
+	code, ok := resp.Meta[statusCodeKey]
+
+	if !ok {
+		return errors.New(resp.Error)
+	}
+
+	c, err := strconv.ParseUint(code, 10, 32)
+
+	if err != nil {
+		return errors.New(resp.Error)
+	}
+
+	s := status.New(status.Code(c), resp.Error)
+
+	if details, ok := resp.Meta[statusDetailsKey]; ok {
+		_ = json.Unmarshal([]byte(details), &s.Details)
+	}
+
+	return s
+}
@@ -0,0 +1,126 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Code 错误码，取值与gRPC保持一致
+type Code uint32
+
+const (
+	OK                Code = 0
+	Canceled          Code = 1
+	Unknown           Code = 2
+	InvalidArgument   Code = 3
+	DeadlineExceeded  Code = 4
+	NotFound          Code = 5
+	PermissionDenied  Code = 7
+	ResourceExhausted Code = 8
+	Unimplemented     Code = 12
+	Internal          Code = 13
+	Unavailable       Code = 14
+	Unauthenticated   Code = 16
+)
+
+var codeNames = map[Code]string{
+	OK:                "OK",
+	Canceled:          "Canceled",
+	Unknown:           "Unknown",
+	InvalidArgument:   "InvalidArgument",
+	DeadlineExceeded:  "DeadlineExceeded",
+	NotFound:          "NotFound",
+	PermissionDenied:  "PermissionDenied",
+	ResourceExhausted: "ResourceExhausted",
+	Unimplemented:     "Unimplemented",
+	Internal:          "Internal",
+	Unavailable:       "Unavailable",
+	Unauthenticated:   "Unauthenticated",
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return "Code(" + strconv.FormatUint(uint64(c), 10) + ")"
+}
+
+// Status 带有错误码的错误，会随响应传递给客户端
+// 服务方法返回*Status时，客户端收到的也是相同错误码的*Status，普通的错误只会传递错误信息
+type Status struct {
+	Code    Code
+	Message string
+	// Details 错误的详细信息，例如服务不存在时列出所有可用的服务
+	Details []string
+}
+
+func New(code Code, msg string) *Status {
+	return &Status{
+		Code:    code,
+		Message: msg,
+	}
+}
+
+func Newf(code Code, format string, a ...any) *Status {
+	return New(code, fmt.Sprintf(format, a...))
+}
+
+// WithDetails 返回一个附带了详细信息的副本
+func (s *Status) WithDetails(details ...string) *Status {
+	res := *s
+	res.Details = append(append([]string(nil), s.Details...), details...)
+	return &res
+}
+
+func (s *Status) Error() string {
+	return s.Message
+}
+
+// Is 错误码相同的*Status视为同一种错误，配合errors.Is使用
+func (s *Status) Is(target error) bool {
+	t, ok := target.(*Status)
+	return ok && t.Code == s.Code
+}
+
+// FromError 从错误链中找出*Status，或者实现了Status() *Status方法的错误
+// 自定义的错误类型实现Status方法之后，同样可以携带错误码传递给客户端
+func FromError(err error) (*Status, bool) {
+	var s *Status
+	if errors.As(err, &s) {
+		return s, true
+	}
+
+	var se interface{ Status() *Status }
+	if errors.As(err, &se) {
+		return se.Status(), true
+	}
+
+	return nil, false
+}
+
+// FromContextError 将ctx的错误转换为对应错误码的*Status，其他错误返回nil
+func FromContextError(err error) *Status {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return New(Canceled, err.Error())
+	}
+	return nil
+}
+
+// CodeOf 返回错误对应的错误码，nil为OK，ctx的错误转换为对应的错误码，其余不带错误码的错误为Unknown
+func CodeOf(err error) Code {
+	if err == nil {
+		return OK
+	}
+	if s, ok := FromError(err); ok {
+		return s.Code
+	}
+	if s := FromContextError(err); s != nil {
+		return s.Code
+	}
+	return Unknown
+}
@@ -0,0 +1,69 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeOf(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{
+			name: "nil",
+			want: OK,
+		},
+		{
+			name: "status",
+			err:  New(NotFound, "not found"),
+			want: NotFound,
+		},
+		{
+			name: "wrapped status",
+			err:  fmt.Errorf("wrap: %w", New(Unimplemented, "unimplemented")),
+			want: Unimplemented,
+		},
+		{
+			name: "deadline",
+			err:  context.DeadlineExceeded,
+			want: DeadlineExceeded,
+		},
+		{
+			name: "canceled",
+			err:  context.Canceled,
+			want: Canceled,
+		},
+		{
+			name: "plain error",
+			err:  errors.New("plain"),
+			want: Unknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, CodeOf(tc.err))
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	s := Newf(Unimplemented, "micro：服务 %q 不存在", "user")
+	d := s.WithDetails("a", "b")
+
+	assert.Empty(t, s.Details)
+	assert.Equal(t, []string{"a", "b"}, d.Details)
+	assert.Equal(t, `micro：服务 "user" 不存在`, d.Error())
+
+	assert.True(t, errors.Is(d, New(Unimplemented, "")))
+	assert.False(t, errors.Is(d, New(NotFound, "")))
+
+	assert.Equal(t, "Unimplemented", Unimplemented.String())
+	assert.Equal(t, "Code(99)", Code(99).String())
+}
@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/compress"
+	"github.com/uzziahlin/transport/rpc/compress/gzip"
+	"github.com/uzziahlin/transport/rpc/serialize/json"
+	"github.com/uzziahlin/transport/rpc/status"
+)
+
+func TestEndPoint_InvokeStatus(t *testing.T) {
+	endpoint := NewEndPoint(":8081")
+	endpoint.Register(&UserServiceImpl{})
+
+	// 通过net.Pipe连接服务端，错误码以及详细信息需要经过编解码
+	constructor := NewProxyConstructor(WithDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go endpoint.handler(server)
+		return client, nil
+	}))
+	t.Cleanup(func() {
+		_ = constructor.Close()
+	})
+
+	constructor.RegisterSerializer(&unknownSerializer{})
+	constructor.RegisterCompressor(&unknownCompressor{})
+
+	caller := constructor.NewCaller("pipe")
+
+	testCases := []struct {
+		name    string
+		ctx     context.Context
+		service string
+		method  string
+		wantErr *status.Status
+	}{
+		{
+			name:    "service not found",
+			ctx:     context.Background(),
+			service: "order-service",
+			method:  "GetById",
+			wantErr: status.New(status.Unimplemented, `micro：服务 "order-service" 不存在`).
+				WithDetails("user-service"),
+		},
+		{
+			name:    "method not found",
+			ctx:     context.Background(),
+			service: "user-service",
+			method:  "GetByName",
+			wantErr: status.New(status.Unimplemented, `micro：服务 "user-service" 没有方法 "GetByName"`).
+				WithDetails("GetById"),
+		},
+		{
+			// Info不是服务方法，不能被远程调用
+			name:    "not a service method",
+			ctx:     context.Background(),
+			service: "user-service",
+			method:  "Info",
+			wantErr: status.New(status.Unimplemented, `micro：服务 "user-service" 没有方法 "Info"`).
+				WithDetails("GetById"),
+		},
+		{
+			name:    "unsupported compressor",
+			ctx:     compress.Context(context.Background(), compress.Type(99)),
+			service: "user-service",
+			method:  "GetById",
+			wantErr: status.New(status.Unimplemented, `micro：不支持的压缩算法 99`).
+				WithDetails("1", "2"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := Invoke[UserReq, UserResp](tc.ctx, caller, tc.service, tc.method, &UserReq{Id: "1"})
+			assert.Equal(t, tc.wantErr, err)
+			assert.Nil(t, res)
+		})
+	}
+
+	t.Run("unsupported serializer", func(t *testing.T) {
+		service := &struct {
+			UserService
+			GetById func(ctx context.Context, req *UserReq) (*UserResp, error) `rpc:"serializer=99"`
+		}{}
+		require.NoError(t, constructor.InitProxy(service))
+
+		_, err := service.GetById(context.Background(), &UserReq{Id: "1"})
+		assert.Equal(t, status.New(status.Unimplemented, `micro：不支持的序列化协议 99`).WithDetails("1", "2"), err)
+		assert.Equal(t, status.Unimplemented, status.CodeOf(err))
+	})
+}
+
+// unknownSerializer 服务端没有注册的序列化协议
+type unknownSerializer struct {
+	json.Serializer
+}
+
+func (u *unknownSerializer) Code() uint8 {
+	return 99
+}
+
+// unknownCompressor 服务端没有注册的压缩算法
+type unknownCompressor struct {
+	gzip.Compressor
+}
+
+func (u *unknownCompressor) Code() uint8 {
+	return 99
+}
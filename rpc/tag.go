@@ -0,0 +1,140 @@
+package rpc
+
+import (
+	"fmt"
+	"github.com/uzziahlin/transport/rpc/compress"
+	"github.com/uzziahlin/transport/rpc/semver"
+	"github.com/uzziahlin/transport/rpc/serialize"
+	"github.com/uzziahlin/transport/rpc/serialize/json"
+	"github.com/uzziahlin/transport/rpc/serialize/proto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 内置序列化协议以及压缩算法的名称，struct tag中既可以使用名称，也可以直接使用编码
+var (
+	serializerNames = map[string]uint8{
+		"json":  (&json.Serializer{}).Code(),
+		"proto": proto.Serializer{}.Code(),
+	}
+
+	compressorNames = map[string]compress.Type{
+		"gzip": compress.GZIP,
+		"zip":  compress.ZIP,
+	}
+)
+
+// methodOptions 方法级别的调用选项
+type methodOptions struct {
+	// name 远端方法名，默认为属性名
+	name string
+	// timeout 每次调用的超时时间，包含重试的时间
+	timeout time.Duration
+	// retries 请求失败时的重试次数，只允许幂等的方法重试
+	retries    int
+	idempotent bool
+	oneway     bool
+	// compressor 默认的压缩算法，调用时通过ctx指定的压缩算法优先
+	compressor compress.Type
+	// serializer 为nil时使用ProxyConstructor默认的序列化协议
+	serializer serialize.Serializer
+	// group以及version来自服务的ServiceInfo，version为期望的版本范围
+	group        string
+	version      string
+	versionRange semver.Range
+}
+
+// parseMethodTag 解析函数属性的rpc tag，tag由逗号分隔的选项组成，例如
+//
+//	GetById func(ctx context.Context, req *UserReq) (*UserResp, error) `rpc:"name=GetUser,timeout=500ms,retry=2,idempotent,compressor=gzip,serializer=proto"`
+//
+// 支持的选项如下:
+//
+//	name=xxx        远端方法名，默认为属性名
+//	timeout=500ms   调用超时时间，使用time.ParseDuration的格式
+//	retry=n         请求失败时的重试次数，要求同时声明idempotent
+//	idempotent      方法是幂等的
+//	oneway          oneway调用
+//	compressor=xxx  压缩算法，gzip、zip或者已注册压缩算法的编码
+//	serializer=xxx  序列化协议，json、proto或者已注册序列化协议的编码
+//
+// 返回不合法的原因，合法则返回空字符串
+func (p *ProxyConstructor) parseMethodTag(field string, tag string) (*methodOptions, string) {
+	opts := &methodOptions{
+		name: field,
+	}
+
+	if tag == "" {
+		return opts, ""
+	}
+
+	for _, item := range strings.Split(tag, ",") {
+		key, val, hasVal := strings.Cut(strings.TrimSpace(item), "=")
+
+		switch key {
+		case "name":
+			if val == "" || strings.ContainsAny(val, "\r\n") {
+				return nil, fmt.Sprintf("rpc tag 方法名 %q 不合法", val)
+			}
+			opts.name = val
+		case "timeout":
+			timeout, err := time.ParseDuration(val)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Sprintf("rpc tag 超时时间 %q 不合法", val)
+			}
+			opts.timeout = timeout
+		case "retry":
+			retries, err := strconv.Atoi(val)
+			if err != nil || retries < 0 {
+				return nil, fmt.Sprintf("rpc tag 重试次数 %q 不合法", val)
+			}
+			opts.retries = retries
+		case "idempotent":
+			if hasVal {
+				return nil, "rpc tag idempotent 不需要指定值"
+			}
+			opts.idempotent = true
+		case "oneway":
+			if hasVal {
+				return nil, "rpc tag oneway 不需要指定值"
+			}
+			opts.oneway = true
+		case "compressor":
+			cTyp, ok := compressorNames[val]
+			if !ok {
+				code, err := strconv.ParseUint(val, 10, 8)
+				if err != nil {
+					return nil, fmt.Sprintf("rpc tag 压缩算法 %q 不合法", val)
+				}
+				cTyp = compress.Type(code)
+			}
+			if _, ok = p.compressors[cTyp]; !ok {
+				return nil, fmt.Sprintf("rpc tag 压缩算法 %q 未注册", val)
+			}
+			opts.compressor = cTyp
+		case "serializer":
+			code, ok := serializerNames[val]
+			if !ok {
+				c, err := strconv.ParseUint(val, 10, 8)
+				if err != nil {
+					return nil, fmt.Sprintf("rpc tag 序列化协议 %q 不合法", val)
+				}
+				code = uint8(c)
+			}
+			serializer, ok := p.serializers[code]
+			if !ok {
+				return nil, fmt.Sprintf("rpc tag 序列化协议 %q 未注册", val)
+			}
+			opts.serializer = serializer
+		default:
+			return nil, fmt.Sprintf("rpc tag 不支持的选项 %q", key)
+		}
+	}
+
+	if opts.retries > 0 && !opts.idempotent {
+		return nil, "rpc tag 只有声明了idempotent的方法才能重试"
+	}
+
+	return opts, ""
+}
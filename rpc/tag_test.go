@@ -0,0 +1,173 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/compress"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/serialize/proto"
+)
+
+func TestProxyConstructor_parseMethodTag(t *testing.T) {
+	testCases := []struct {
+		name       string
+		tag        string
+		wantOpts   *methodOptions
+		wantReason string
+	}{
+		{
+			name:     "empty",
+			tag:      "",
+			wantOpts: &methodOptions{name: "GetById"},
+		},
+		{
+			name: "all",
+			tag:  "name=GetUser, timeout=500ms, retry=2, idempotent, oneway, compressor=gzip, serializer=proto",
+			wantOpts: &methodOptions{
+				name:       "GetUser",
+				timeout:    500 * time.Millisecond,
+				retries:    2,
+				idempotent: true,
+				oneway:     true,
+				compressor: compress.GZIP,
+				serializer: &proto.Serializer{},
+			},
+		},
+		{
+			name:     "code",
+			tag:      "compressor=2,serializer=1",
+			wantOpts: &methodOptions{name: "GetById", compressor: compress.ZIP, serializer: NewProxyConstructor().serializer},
+		},
+		{
+			name:       "invalid timeout",
+			tag:        "timeout=abc",
+			wantReason: `rpc tag 超时时间 "abc" 不合法`,
+		},
+		{
+			name:       "retry without idempotent",
+			tag:        "retry=3",
+			wantReason: "rpc tag 只有声明了idempotent的方法才能重试",
+		},
+		{
+			name:       "unregistered compressor",
+			tag:        "compressor=9",
+			wantReason: `rpc tag 压缩算法 "9" 未注册`,
+		},
+		{
+			name:       "unknown serializer",
+			tag:        "serializer=xml",
+			wantReason: `rpc tag 序列化协议 "xml" 不合法`,
+		},
+		{
+			name:       "unknown option",
+			tag:        "cache=true",
+			wantReason: `rpc tag 不支持的选项 "cache"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, reason := NewProxyConstructor().parseMethodTag("GetById", tc.tag)
+			assert.Equal(t, tc.wantReason, reason)
+			assert.Equal(t, tc.wantOpts, opts)
+		})
+	}
+}
+
+func TestProxyConstructor_InitProxyTagErr(t *testing.T) {
+	err := NewProxyConstructor().InitProxy(&invalidTagService{})
+
+	var vErr *ValidationError
+	require.ErrorAs(t, err, &vErr)
+	require.Len(t, vErr.Fields, 2)
+	assert.Equal(t, "Retry", vErr.Fields[0].Field)
+	assert.Equal(t, "Timeout", vErr.Fields[1].Field)
+}
+
+func TestProxyConstructor_Tag(t *testing.T) {
+	endpoint := NewEndPoint(":8081")
+	endpoint.Register(&UserServiceImpl{})
+
+	proxy := &recordProxy{next: endpoint, failures: 2}
+
+	service := &taggedService{}
+	err := NewProxyConstructor().setFuncField(service, proxy)
+	require.NoError(t, err)
+
+	// 远端方法名被重写为GetById，并且使用tag指定的压缩算法
+	res, err := service.Get(context.Background(), &UserReq{Id: "tag"})
+	require.NoError(t, err)
+	assert.Equal(t, "response: tag", res.Content)
+	assert.Equal(t, "GetById", proxy.last.MethodName)
+	assert.Equal(t, uint8(compress.GZIP), proxy.last.Compressor)
+	assert.Contains(t, proxy.last.Meta, "sys_timeout")
+	// 前两次请求失败，重试两次之后成功
+	assert.Equal(t, 3, proxy.calls)
+
+	proxy.calls, proxy.failures = 0, 5
+	_, err = service.Get(context.Background(), &UserReq{Id: "tag"})
+	assert.Equal(t, errFakeNetwork, err)
+	assert.Equal(t, 3, proxy.calls)
+
+	// 不幂等的方法不会重试
+	proxy.calls, proxy.failures = 0, 1
+	_, err = service.GetById(context.Background(), &UserReq{Id: "tag"})
+	assert.Equal(t, errFakeNetwork, err)
+	assert.Equal(t, 1, proxy.calls)
+
+	proxy.calls, proxy.failures = 0, 0
+	_, _ = service.Notify(context.Background(), &UserReq{Id: "tag"})
+	assert.True(t, proxy.last.IsOneway())
+	assert.Equal(t, "GetById", proxy.last.MethodName)
+}
+
+var errFakeNetwork = errors.New("fake network error")
+
+// recordProxy 记录最后一次请求，并且让前failures次请求失败
+type recordProxy struct {
+	next     Proxy
+	failures int
+	calls    int
+	last     *message.Request
+}
+
+func (r *recordProxy) Invoke(ctx context.Context, req *message.Request) (*message.Response, error) {
+	r.calls++
+	r.last = req
+
+	if r.failures > 0 {
+		r.failures--
+		return nil, errFakeNetwork
+	}
+
+	return r.next.Invoke(ctx, req)
+}
+
+type taggedService struct {
+	Get     func(ctx context.Context, req *UserReq) (*UserResp, error) `rpc:"name=GetById,timeout=1s,retry=2,idempotent,compressor=gzip"`
+	GetById func(ctx context.Context, req *UserReq) (*UserResp, error)
+	Notify  func(ctx context.Context, req *UserReq) (*UserResp, error) `rpc:"name=GetById,oneway"`
+	Skipped func(ctx context.Context) error                            `rpc:"-"`
+}
+
+func (t *taggedService) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "user-service",
+	}
+}
+
+type invalidTagService struct {
+	Retry   func(ctx context.Context, req *UserReq) (*UserResp, error) `rpc:"retry=1"`
+	Timeout func(ctx context.Context, req *UserReq) (*UserResp, error) `rpc:"timeout=-1s"`
+}
+
+func (i *invalidTagService) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "invalid-service",
+	}
+}
@@ -0,0 +1,119 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"time"
+)
+
+// tlsHandshakeTimeout 服务端TLS握手的超时时间
+const tlsHandshakeTimeout = 10 * time.Second
+
+// WithTLS 客户端使用TLS连接服务端，cfg没有指定ServerName时使用服务地址中的host，unix domain socket使用localhost
+// 双向TLS时在cfg中设置客户端证书，例如Certificates或者GetClientCertificate
+func WithTLS(cfg *tls.Config) ConstructorOpt {
+	return func(c *ProxyConstructor) {
+		c.tlsConfig = cfg
+	}
+}
+
+// WithServerTLS 服务端使用TLS，Startup以及Serve接收的连接都会使用TLS
+// 双向TLS时将cfg.ClientAuth设置为tls.RequireAndVerifyClientCert，并设置ClientCAs
+func WithServerTLS(cfg *tls.Config) EndPointOpt {
+	return func(e *EndPoint) {
+		e.tlsConfig = cfg
+	}
+}
+
+// Identity 对端证书中的身份信息，只有证书通过了校验才会有值
+type Identity struct {
+	Subject        pkix.Name
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL
+}
+
+// identityFromState 从通过校验的证书链中获取对端的身份
+func identityFromState(state tls.ConnectionState) *Identity {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	cert := state.VerifiedChains[0][0]
+
+	return &Identity{
+		Subject:        cert.Subject,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           cert.URIs,
+	}
+}
+
+// tlsDialer 在dial建立的连接之上完成TLS握手
+func tlsDialer(dial Dialer, cfg *tls.Config) Dialer {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, addr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		c := cfg
+
+		if c.ServerName == "" {
+			c = cfg.Clone()
+			c.ServerName = serverName(addr)
+		}
+
+		tlsConn := tls.Client(conn, c)
+
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+
+		return tlsConn, nil
+	}
+}
+
+// serverName 返回校验服务端证书时使用的域名，unix domain socket连接的是本机，使用localhost
+func serverName(addr string) string {
+	network, address := splitAddr(addr)
+	if network == "unix" {
+		return "localhost"
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
+
+// handshake 服务端完成TLS握手，并将连接状态以及对端身份写入p
+// 不是TLS连接时直接返回
+func handshake(conn net.Conn, p *Peer) error {
+	tlsConn, ok := conn.(*tls.Conn)
+
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tlsHandshakeTimeout)
+	defer cancel()
+
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return err
+	}
+
+	state := tlsConn.ConnectionState()
+	p.TLS = &state
+	p.Identity = identityFromState(state)
+
+	return nil
+}
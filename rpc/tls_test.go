@@ -0,0 +1,115 @@
+package rpc_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/uzziahlin/transport/rpc"
+	"github.com/uzziahlin/transport/rpc/rpctest"
+	"github.com/uzziahlin/transport/rpc/tlsconfig"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLS(t *testing.T) {
+	t.Parallel()
+
+	certs := rpctest.NewCerts(t)
+
+	serverCert, err := tlsconfig.NewCertReloader(certs.ServerCert, certs.ServerKey)
+	require.NoError(t, err)
+	clientCert, err := tlsconfig.NewCertReloader(certs.ClientCert, certs.ClientKey)
+	require.NoError(t, err)
+
+	serverTLS, err := tlsconfig.Server(serverCert, nil)
+	require.NoError(t, err)
+	serverMTLS, err := tlsconfig.Server(serverCert, certs.CAPool)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		serverTLS *tls.Config
+		clientOpt []rpc.ConstructorOpt
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "tls",
+			serverTLS: serverTLS,
+			clientOpt: []rpc.ConstructorOpt{rpc.WithTLS(tlsconfig.Client(certs.CAPool, nil))},
+			want:      "anonymous",
+		},
+		{
+			name:      "mutual tls",
+			serverTLS: serverMTLS,
+			clientOpt: []rpc.ConstructorOpt{rpc.WithTLS(tlsconfig.Client(certs.CAPool, clientCert))},
+			want:      "client",
+		},
+		{
+			name:      "mutual tls without client cert",
+			serverTLS: serverMTLS,
+			clientOpt: []rpc.ConstructorOpt{rpc.WithTLS(tlsconfig.Client(certs.CAPool, nil))},
+			wantErr:   true,
+		},
+		{
+			name:      "untrusted server",
+			serverTLS: serverTLS,
+			clientOpt: []rpc.ConstructorOpt{rpc.WithTLS(tlsconfig.Client(x509.NewCertPool(), nil))},
+			wantErr:   true,
+		},
+		{
+			name:      "plaintext client",
+			serverTLS: serverTLS,
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			endpoint := rpc.NewEndPoint("", rpc.WithServerTLS(tc.serverTLS))
+			endpoint.Register(&identityService{})
+
+			server := rpctest.NewServer(t, endpoint)
+
+			userService := &rpc.UserService{}
+			require.NoError(t, server.NewConstructor(tc.clientOpt...).InitProxy(userService))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			resp, err := userService.GetById(ctx, &rpc.UserReq{Id: "1"})
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, resp.Content)
+		})
+	}
+}
+
+// identityService 返回客户端证书的CN，没有客户端证书时返回anonymous
+type identityService struct{}
+
+func (s *identityService) GetById(ctx context.Context, req *rpc.UserReq) (*rpc.UserResp, error) {
+	p, ok := rpc.PeerFromContext(ctx)
+	if !ok || p.TLS == nil {
+		return &rpc.UserResp{Content: "plaintext"}, nil
+	}
+	if p.Identity == nil {
+		return &rpc.UserResp{Content: "anonymous"}, nil
+	}
+	return &rpc.UserResp{Content: p.Identity.Subject.CommonName}, nil
+}
+
+func (s *identityService) Info() rpc.ServiceInfo {
+	return rpc.ServiceInfo{
+		ServiceName: "user-service",
+	}
+}
@@ -0,0 +1,62 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LoadCertPool 从PEM格式的文件中加载CA证书
+func LoadCertPool(files ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("micro：文件 %s 中没有合法的证书", file)
+		}
+	}
+
+	return pool, nil
+}
+
+// Server 创建服务端的TLS配置，证书由reloader提供
+// clientCAs不为nil时开启双向TLS，要求客户端提供由clientCAs签发的证书
+func Server(reloader *CertReloader, clientCAs *x509.CertPool) (*tls.Config, error) {
+	if reloader == nil {
+		return nil, errors.New("micro：服务端必须提供证书")
+	}
+
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if clientCAs != nil {
+		cfg.ClientCAs = clientCAs
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// Client 创建客户端的TLS配置，rootCAs为nil时使用系统的CA证书
+// reloader不为nil时在双向TLS中向服务端提供客户端证书
+func Client(rootCAs *x509.CertPool, reloader *CertReloader) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    rootCAs,
+	}
+
+	if reloader != nil {
+		cfg.GetClientCertificate = reloader.GetClientCertificate
+	}
+
+	return cfg
+}
@@ -0,0 +1,116 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+)
+
+type ReloaderOpt func(r *CertReloader)
+
+// WithCheckInterval 设置检查证书文件是否发生变化的最小间隔，默认为defaultCheckInterval
+// 间隔为0时每次握手都会检查
+func WithCheckInterval(d time.Duration) ReloaderOpt {
+	return func(r *CertReloader) {
+		r.interval = d
+	}
+}
+
+const defaultCheckInterval = 10 * time.Second
+
+// CertReloader 从磁盘加载证书，证书文件发生变化之后自动重新加载，更换证书不需要重启进程
+// 检查发生在TLS握手时，不需要额外的g，新证书加载失败时继续使用旧的证书
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+// NewCertReloader 加载证书，第一次加载失败时返回错误
+func NewCertReloader(certFile, keyFile string, opts ...ReloaderOpt) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: defaultCheckInterval,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.load(certMod, keyMod); err != nil {
+		return nil, err
+	}
+
+	r.lastCheck = time.Now()
+
+	return r, nil
+}
+
+// GetCertificate 用于服务端的tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// GetClientCertificate 用于客户端的tls.Config.GetClientCertificate
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// Certificate 返回当前的证书，距离上一次检查超过了间隔时会先检查文件是否发生变化
+func (r *CertReloader) Certificate() *tls.Certificate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.lastCheck) >= r.interval {
+		r.lastCheck = time.Now()
+
+		certMod, keyMod, err := r.modTimes()
+		if err == nil && (!certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod)) {
+			// 证书和私钥可能没有同时更新完，加载失败时继续使用旧的证书，下一次检查时重试
+			_ = r.load(certMod, keyMod)
+		}
+	}
+
+	return r.cert
+}
+
+// load 调用方需要持有锁，或者处于初始化阶段
+func (r *CertReloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+
+	return nil
+}
+
+func (r *CertReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
@@ -0,0 +1,70 @@
+package tlsconfig
+
+import (
+	"crypto/x509"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/rpctest"
+)
+
+func TestCertReloader(t *testing.T) {
+	certs := rpctest.NewCerts(t)
+
+	r, err := NewCertReloader(certs.ServerCert, certs.ServerKey, WithCheckInterval(0))
+	require.NoError(t, err)
+
+	assert.Equal(t, "server", commonName(t, r))
+
+	// 覆盖证书文件，并修改文件的修改时间，避免文件系统的时间精度导致检测不到变化
+	certs.WriteCert(t, "server-v2", certs.ServerCert, certs.ServerKey)
+	touch(t, certs.ServerCert, certs.ServerKey)
+
+	assert.Equal(t, "server-v2", commonName(t, r))
+
+	// 新证书加载失败时继续使用旧的证书
+	require.NoError(t, os.WriteFile(certs.ServerKey, []byte("broken"), 0o600))
+	touch(t, certs.ServerKey)
+
+	assert.Equal(t, "server-v2", commonName(t, r))
+}
+
+func TestCertReloader_CheckInterval(t *testing.T) {
+	certs := rpctest.NewCerts(t)
+
+	r, err := NewCertReloader(certs.ServerCert, certs.ServerKey, WithCheckInterval(time.Hour))
+	require.NoError(t, err)
+
+	certs.WriteCert(t, "server-v2", certs.ServerCert, certs.ServerKey)
+	touch(t, certs.ServerCert, certs.ServerKey)
+
+	// 没有到检查的间隔，继续使用旧的证书
+	assert.Equal(t, "server", commonName(t, r))
+}
+
+func TestNewCertReloader_Error(t *testing.T) {
+	_, err := NewCertReloader("not-exist.pem", "not-exist-key.pem")
+	assert.Error(t, err)
+}
+
+func commonName(t *testing.T, r *CertReloader) string {
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf.Subject.CommonName
+}
+
+var touchTime = time.Now()
+
+func touch(t *testing.T, files ...string) {
+	touchTime = touchTime.Add(time.Minute)
+	for _, f := range files {
+		require.NoError(t, os.Chtimes(f, touchTime, touchTime))
+	}
+}
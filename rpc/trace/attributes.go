@@ -0,0 +1,21 @@
+package trace
+
+// rpc调用相关的span属性
+const (
+	AttrSystem     = "rpc.system"
+	AttrService    = "rpc.service"
+	AttrMethod     = "rpc.method"
+	AttrSerializer = "rpc.serializer"
+	AttrCompressor = "rpc.compressor"
+	AttrOneway     = "rpc.oneway"
+	// AttrRequestSize 压缩前的请求大小
+	AttrRequestSize = "rpc.request.size"
+	// AttrRequestWireSize 压缩后实际传输的请求大小
+	AttrRequestWireSize = "rpc.request.wire_size"
+	// AttrResponseSize 压缩前的响应大小
+	AttrResponseSize = "rpc.response.size"
+	// AttrResponseWireSize 压缩后实际传输的响应大小
+	AttrResponseWireSize = "rpc.response.wire_size"
+
+	SystemName = "transport"
+)
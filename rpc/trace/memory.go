@@ -0,0 +1,38 @@
+package trace
+
+import "sync"
+
+// InMemoryExporter 将span保存在内存中，主要用于测试
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (m *InMemoryExporter) Export(span *Span) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.spans = append(m.spans, span)
+}
+
+// Spans 按照结束的顺序返回所有导出的span
+func (m *InMemoryExporter) Spans() []*Span {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make([]*Span, len(m.spans))
+	copy(res, m.spans)
+
+	return res
+}
+
+func (m *InMemoryExporter) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.spans = nil
+}
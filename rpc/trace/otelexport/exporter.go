@@ -0,0 +1,194 @@
+// Package otelexport 将框架产生的span转换为OpenTelemetry的span，
+// 从而可以复用OpenTelemetry生态中的SpanExporter，例如OTLP、Jaeger以及stdout
+package otelexport
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"time"
+
+	"github.com/uzziahlin/transport/rpc/trace"
+)
+
+const instrumentationName = "github.com/uzziahlin/transport/rpc"
+
+type Option func(e *Exporter)
+
+// WithResource 设置导出span时携带的资源信息，例如服务名
+func WithResource(res *resource.Resource) Option {
+	return func(e *Exporter) {
+		e.resource = res
+	}
+}
+
+// WithBatchOptions 设置批量导出的参数，例如队列长度、批次大小以及导出超时时间，
+// 默认值与sdktrace.NewBatchSpanProcessor相同
+func WithBatchOptions(opts ...sdktrace.BatchSpanProcessorOption) Option {
+	return func(e *Exporter) {
+		e.batchOpts = append(e.batchOpts, opts...)
+	}
+}
+
+// Exporter 实现了trace.Exporter，span会保留原有的trace-id以及span-id
+// span结束时只放入有界的队列，由sdktrace.BatchSpanProcessor在后台批量调用SpanExporter，
+// 不会阻塞调用。队列已满时丢弃span，导出失败的错误交给otel.Handle处理
+type Exporter struct {
+	processor sdktrace.SpanProcessor
+	resource  *resource.Resource
+	batchOpts []sdktrace.BatchSpanProcessorOption
+}
+
+func NewExporter(exporter sdktrace.SpanExporter, opts ...Option) *Exporter {
+	res := &Exporter{
+		resource: resource.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	res.processor = sdktrace.NewBatchSpanProcessor(exporter, res.batchOpts...)
+
+	return res
+}
+
+func (e *Exporter) Export(span *trace.Span) {
+	e.processor.OnEnd(e.convert(span))
+}
+
+// ForceFlush 导出队列中所有的span
+func (e *Exporter) ForceFlush(ctx context.Context) error {
+	return e.processor.ForceFlush(ctx)
+}
+
+// Shutdown 导出队列中剩余的span并关闭底层的SpanExporter，之后导出的span会被丢弃
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	return e.processor.Shutdown(ctx)
+}
+
+func (e *Exporter) convert(span *trace.Span) sdktrace.ReadOnlySpan {
+	attrs := make([]attribute.KeyValue, 0, len(span.Attributes))
+
+	for k, v := range span.Attributes {
+		attrs = append(attrs, toAttribute(k, v))
+	}
+
+	return &readOnlySpan{
+		name:        span.Name,
+		spanContext: toSpanContext(span.SpanContext),
+		parent:      toSpanContext(span.Parent),
+		kind:        toSpanKind(span.Kind),
+		startTime:   span.StartTime,
+		endTime:     span.EndTime,
+		attributes:  attrs,
+		status: sdktrace.Status{
+			Code:        toCode(span.Status),
+			Description: span.StatusMessage,
+		},
+		resource: e.resource,
+	}
+}
+
+// readOnlySpan 已经结束的span的快照
+// sdktrace.ReadOnlySpan包含未导出的方法，只能通过嵌入接口实现，嵌入的接口始终为nil，所有导出的方法都需要实现
+type readOnlySpan struct {
+	sdktrace.ReadOnlySpan
+
+	name        string
+	spanContext oteltrace.SpanContext
+	parent      oteltrace.SpanContext
+	kind        oteltrace.SpanKind
+	startTime   time.Time
+	endTime     time.Time
+	attributes  []attribute.KeyValue
+	status      sdktrace.Status
+	resource    *resource.Resource
+}
+
+func (s *readOnlySpan) Name() string                       { return s.name }
+func (s *readOnlySpan) SpanContext() oteltrace.SpanContext { return s.spanContext }
+func (s *readOnlySpan) Parent() oteltrace.SpanContext      { return s.parent }
+func (s *readOnlySpan) SpanKind() oteltrace.SpanKind       { return s.kind }
+func (s *readOnlySpan) StartTime() time.Time               { return s.startTime }
+func (s *readOnlySpan) EndTime() time.Time                 { return s.endTime }
+func (s *readOnlySpan) Attributes() []attribute.KeyValue   { return s.attributes }
+func (s *readOnlySpan) Links() []sdktrace.Link             { return nil }
+func (s *readOnlySpan) Events() []sdktrace.Event           { return nil }
+func (s *readOnlySpan) Status() sdktrace.Status            { return s.status }
+func (s *readOnlySpan) Resource() *resource.Resource       { return s.resource }
+func (s *readOnlySpan) DroppedAttributes() int             { return 0 }
+func (s *readOnlySpan) DroppedLinks() int                  { return 0 }
+func (s *readOnlySpan) DroppedEvents() int                 { return 0 }
+func (s *readOnlySpan) ChildSpanCount() int                { return 0 }
+func (s *readOnlySpan) InstrumentationScope() instrumentation.Scope {
+	return instrumentation.Scope{Name: instrumentationName}
+}
+
+func (s *readOnlySpan) InstrumentationLibrary() instrumentation.Library {
+	return instrumentation.Library{Name: instrumentationName}
+}
+
+func toSpanContext(sc trace.SpanContext) oteltrace.SpanContext {
+	if !sc.IsValid() {
+		return oteltrace.SpanContext{}
+	}
+
+	state, err := oteltrace.ParseTraceState(sc.TraceState)
+
+	if err != nil {
+		state = oteltrace.TraceState{}
+	}
+
+	return oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID(sc.TraceID),
+		SpanID:     oteltrace.SpanID(sc.SpanID),
+		TraceFlags: oteltrace.TraceFlags(sc.Flags),
+		TraceState: state,
+		Remote:     sc.Remote,
+	})
+}
+
+func toSpanKind(kind trace.SpanKind) oteltrace.SpanKind {
+	switch kind {
+	case trace.SpanKindServer:
+		return oteltrace.SpanKindServer
+	case trace.SpanKindClient:
+		return oteltrace.SpanKindClient
+	default:
+		return oteltrace.SpanKindInternal
+	}
+}
+
+func toCode(code trace.StatusCode) codes.Code {
+	switch code {
+	case trace.StatusOK:
+		return codes.Ok
+	case trace.StatusError:
+		return codes.Error
+	default:
+		return codes.Unset
+	}
+}
+
+func toAttribute(key string, val any) attribute.KeyValue {
+	switch v := val.(type) {
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
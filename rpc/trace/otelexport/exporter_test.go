@@ -0,0 +1,104 @@
+package otelexport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/uzziahlin/transport/rpc/trace"
+)
+
+func TestExporter_Export(t *testing.T) {
+	otelExporter := tracetest.NewInMemoryExporter()
+
+	exporter := NewExporter(otelExporter)
+	tracer := trace.NewTracer(exporter)
+
+	ctx, parent := tracer.Start(context.Background(), "user-service/GetById", trace.SpanKindClient)
+	_, child := tracer.Start(ctx, "user-service/GetById", trace.SpanKindServer)
+
+	child.SetAttribute(trace.AttrService, "user-service")
+	child.SetAttribute(trace.AttrRequestSize, 12)
+	child.RecordError(errors.New("this is the err"))
+	child.End()
+	parent.End()
+
+	require.NoError(t, exporter.ForceFlush(context.Background()))
+
+	spans := otelExporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	got := spans[0]
+	assert.Equal(t, "user-service/GetById", got.Name)
+	assert.Equal(t, oteltrace.SpanKindServer, got.SpanKind)
+	assert.Equal(t, oteltrace.TraceID(child.SpanContext.TraceID), got.SpanContext.TraceID())
+	assert.Equal(t, oteltrace.SpanID(child.SpanContext.SpanID), got.SpanContext.SpanID())
+	assert.Equal(t, oteltrace.SpanID(parent.SpanContext.SpanID), got.Parent.SpanID())
+	assert.True(t, got.SpanContext.IsSampled())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String(trace.AttrService, "user-service"),
+		attribute.Int(trace.AttrRequestSize, 12),
+	}, got.Attributes)
+	assert.Equal(t, codes.Error, got.Status.Code)
+	assert.Equal(t, "this is the err", got.Status.Description)
+
+	assert.False(t, spans[1].Parent.IsValid())
+	assert.Equal(t, instrumentationName, got.InstrumentationLibrary.Name)
+
+	require.NoError(t, exporter.Shutdown(context.Background()))
+}
+
+// blockingExporter 导出时阻塞直到release被关闭，模拟网络很慢的SpanExporter
+type blockingExporter struct {
+	release chan struct{}
+	*tracetest.InMemoryExporter
+}
+
+func (b *blockingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return b.InMemoryExporter.ExportSpans(ctx, spans)
+}
+
+// TestExporter_Async span结束时不会等待SpanExporter导出
+func TestExporter_Async(t *testing.T) {
+	otelExporter := &blockingExporter{
+		release:          make(chan struct{}),
+		InMemoryExporter: tracetest.NewInMemoryExporter(),
+	}
+
+	exporter := NewExporter(otelExporter, WithBatchOptions(sdktrace.WithMaxExportBatchSize(1)))
+	tracer := trace.NewTracer(exporter)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			_, span := tracer.Start(context.Background(), "user-service/GetById", trace.SpanKindServer)
+			span.End()
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("span结束时被SpanExporter阻塞")
+	}
+
+	close(otelExporter.release)
+	require.NoError(t, exporter.ForceFlush(context.Background()))
+	assert.Len(t, otelExporter.GetSpans(), 3)
+	require.NoError(t, exporter.Shutdown(context.Background()))
+}
@@ -0,0 +1,164 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// TraceparentKey W3C Trace Context 在请求元数据中使用的key
+	TraceparentKey = "traceparent"
+	TracestateKey  = "tracestate"
+
+	traceparentVersion = "00"
+
+	// FlagsSampled 采样标记
+	FlagsSampled byte = 0x01
+)
+
+type TraceID [16]byte
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+type SpanID [8]byte
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext 需要在服务之间传递的span信息
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	// Remote 是否是从上游服务解析得到的
+	Remote bool
+}
+
+func (s SpanContext) IsValid() bool {
+	return s.TraceID.IsValid() && s.SpanID.IsValid()
+}
+
+func (s SpanContext) IsSampled() bool {
+	return s.Flags&FlagsSampled == FlagsSampled
+}
+
+// Traceparent 按照W3C Trace Context格式输出，形如 00-{trace-id}-{span-id}-{flags}
+func (s SpanContext) Traceparent() string {
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, s.TraceID, s.SpanID, s.Flags)
+}
+
+// ParseTraceparent 解析W3C Trace Context格式的traceparent
+func ParseTraceparent(traceparent string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+
+	if len(parts) < 4 {
+		return sc, fmt.Errorf("micro：traceparent %q 格式不合法", traceparent)
+	}
+
+	// 版本为00时必须恰好为4段，更高的版本允许在后边追加字段
+	if len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == traceparentVersion && len(parts) != 4) {
+		return sc, fmt.Errorf("micro：traceparent %q 版本不合法", traceparent)
+	}
+
+	if err := decodeHex(parts[1], sc.TraceID[:]); err != nil {
+		return sc, fmt.Errorf("micro：traceparent %q trace-id不合法", traceparent)
+	}
+
+	if err := decodeHex(parts[2], sc.SpanID[:]); err != nil {
+		return sc, fmt.Errorf("micro：traceparent %q parent-id不合法", traceparent)
+	}
+
+	var flags [1]byte
+
+	if err := decodeHex(parts[3], flags[:]); err != nil {
+		return sc, fmt.Errorf("micro：traceparent %q trace-flags不合法", traceparent)
+	}
+
+	sc.Flags = flags[0]
+
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("micro：traceparent %q trace-id以及parent-id不能全为0", traceparent)
+	}
+
+	return sc, nil
+}
+
+func decodeHex(s string, dst []byte) error {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return errors.New("invalid hex")
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+// Inject 将ctx中的span信息写入请求元数据
+func Inject(ctx context.Context, meta map[string]string) {
+	sc := SpanContextFromContext(ctx)
+
+	if !sc.IsValid() {
+		return
+	}
+
+	meta[TraceparentKey] = sc.Traceparent()
+
+	if sc.TraceState != "" {
+		meta[TracestateKey] = sc.TraceState
+	}
+}
+
+// Extract 从请求元数据中解析上游服务的span信息，并作为远端的父span放入ctx
+func Extract(ctx context.Context, meta map[string]string) context.Context {
+	traceparent, ok := meta[TraceparentKey]
+
+	if !ok {
+		return ctx
+	}
+
+	sc, err := ParseTraceparent(traceparent)
+
+	if err != nil {
+		return ctx
+	}
+
+	sc.TraceState = meta[TracestateKey]
+
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+type remoteKey struct{}
+
+// ContextWithRemoteSpanContext 将上游服务传递过来的span信息放入ctx，并作为当前的span信息
+// ctx中已有的本地span会被屏蔽
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	ctx = ContextWithSpan(ctx, nil)
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext 获取ctx中当前的span信息，优先返回本地的span
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext
+	}
+
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+
+	return sc
+}
@@ -0,0 +1,123 @@
+package trace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	testCases := []struct {
+		name        string
+		traceparent string
+		wantSC      SpanContext
+		wantErr     bool
+	}{
+		{
+			name:        "sampled",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantSC: SpanContext{
+				TraceID: TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+				SpanID:  SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+				Flags:   FlagsSampled,
+			},
+		},
+		{
+			name:        "future version",
+			traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra",
+			wantSC: SpanContext{
+				TraceID: TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+				SpanID:  SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+			},
+		},
+		{
+			name:        "too short",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-01",
+			wantErr:     true,
+		},
+		{
+			name:        "extra field in version 00",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			wantErr:     true,
+		},
+		{
+			name:        "upper case",
+			traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			wantErr:     true,
+		},
+		{
+			name:        "zero trace id",
+			traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tc.traceparent)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantSC, sc)
+		})
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	ctx, span := tracer.Start(context.Background(), "client", SpanKindClient)
+	span.SpanContext.TraceState = "k=v"
+
+	meta := make(map[string]string)
+	Inject(ctx, meta)
+
+	assert.Equal(t, span.SpanContext.Traceparent(), meta[TraceparentKey])
+	assert.Equal(t, "k=v", meta[TracestateKey])
+
+	// 服务端span作为客户端span的子span
+	serverCtx, serverSpan := tracer.Start(Extract(context.Background(), meta), "server", SpanKindServer)
+
+	assert.Equal(t, span.SpanContext.TraceID, serverSpan.SpanContext.TraceID)
+	assert.Equal(t, span.SpanContext.SpanID, serverSpan.Parent.SpanID)
+	assert.True(t, serverSpan.Parent.Remote)
+	assert.Equal(t, "k=v", serverSpan.SpanContext.TraceState)
+	assert.Equal(t, serverSpan.SpanContext, SpanContextFromContext(serverCtx))
+
+	serverSpan.End()
+	span.End()
+	span.End()
+
+	assert.Len(t, exporter.Spans(), 2)
+}
+
+func TestTracer_Start(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+
+	// 父span没有被采样，子span也不会被导出
+	parent := SpanContext{
+		TraceID: TraceID{1},
+		SpanID:  SpanID{1},
+	}
+
+	_, span := tracer.Start(ContextWithRemoteSpanContext(context.Background(), parent), "server", SpanKindServer)
+	span.End()
+
+	assert.False(t, span.SpanContext.IsSampled())
+	assert.Empty(t, exporter.Spans())
+
+	// nil tracer返回nil span，所有操作都是空操作
+	var nilTracer *Tracer
+	ctx, nilSpan := nilTracer.Start(context.Background(), "noop", SpanKindClient)
+	assert.Nil(t, nilSpan)
+	assert.Nil(t, SpanFromContext(ctx))
+	nilSpan.SetAttribute("k", "v")
+	nilSpan.RecordError(nil)
+	nilSpan.End()
+}
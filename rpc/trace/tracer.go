@@ -0,0 +1,187 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+type SpanKind uint8
+
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+type StatusCode uint8
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// Exporter 导出已经结束的span，实现需要保证并发安全
+type Exporter interface {
+	Export(span *Span)
+}
+
+// Tracer 负责创建span，并在span结束时交给Exporter导出
+type Tracer struct {
+	exporter Exporter
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+	}
+}
+
+// Start 创建一个新的span，ctx中存在span时作为其子span，否则开启一条新的链路
+// 父span没有被采样时，新的span也不会被采样，不会被导出
+// t为nil时不创建span，返回的nil span可以安全地调用所有方法
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		Parent:     parent,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]any, 8),
+		tracer:     t,
+	}
+
+	span.SpanContext = SpanContext{
+		TraceID:    parent.TraceID,
+		Flags:      FlagsSampled,
+		TraceState: parent.TraceState,
+	}
+
+	if parent.IsValid() {
+		span.SpanContext.Flags = parent.Flags
+	} else {
+		_, _ = rand.Read(span.SpanContext.TraceID[:])
+	}
+
+	_, _ = rand.Read(span.SpanContext.SpanID[:])
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Span 一次调用在链路中的记录，nil span的所有方法都是空操作
+type Span struct {
+	Name        string
+	Kind        SpanKind
+	SpanContext SpanContext
+	// Parent 父span的信息，开启新链路时为零值
+	Parent     SpanContext
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]any
+	Status     StatusCode
+	// StatusMessage 状态为StatusError时的错误信息
+	StatusMessage string
+
+	tracer *Tracer
+	mu     sync.Mutex
+	ended  bool
+}
+
+// SetAttribute 设置属性，span结束之后设置无效
+func (s *Span) SetAttribute(key string, val any) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	s.Attributes[key] = val
+}
+
+// SetStatus 设置状态，span结束之后设置无效
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended {
+		return
+	}
+
+	s.Status = code
+	s.StatusMessage = msg
+}
+
+// RecordError 记录错误，err为nil时将状态设置为StatusOK
+func (s *Span) RecordError(err error) {
+	if s == nil {
+		return
+	}
+
+	if err != nil {
+		s.SetStatus(StatusError, err.Error())
+		return
+	}
+	s.SetStatus(StatusOK, "")
+}
+
+// End 结束span，如果span被采样则导出，重复调用无效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+
+	s.ended = true
+	s.EndTime = time.Now()
+
+	s.mu.Unlock()
+
+	if s.SpanContext.IsSampled() && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(s)
+	}
+}
+
+type spanKey struct{}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext 获取ctx中本地的span，不存在时返回nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
@@ -0,0 +1,39 @@
+package rpc
+
+import (
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/trace"
+)
+
+func spanName(service, method string) string {
+	return service + "/" + method
+}
+
+// setRequestAttributes 记录请求相关的span属性
+func setRequestAttributes(span *trace.Span, req *message.Request) {
+	span.SetAttribute(trace.AttrSystem, trace.SystemName)
+	span.SetAttribute(trace.AttrService, req.ServiceName)
+	span.SetAttribute(trace.AttrMethod, req.MethodName)
+	span.SetAttribute(trace.AttrSerializer, int(req.Serializer))
+	span.SetAttribute(trace.AttrCompressor, int(req.Compressor))
+	span.SetAttribute(trace.AttrOneway, req.IsOneway())
+}
+
+// setStatsAttributes 记录请求以及响应在压缩前后的大小，未知的大小不记录
+func setStatsAttributes(span *trace.Span, stats *callStats) {
+	sizes := []struct {
+		key  string
+		size int
+	}{
+		{key: trace.AttrRequestSize, size: stats.reqSize},
+		{key: trace.AttrRequestWireSize, size: stats.reqWireSize},
+		{key: trace.AttrResponseSize, size: stats.respSize},
+		{key: trace.AttrResponseWireSize, size: stats.respWireSize},
+	}
+
+	for _, s := range sizes {
+		if s.size >= 0 {
+			span.SetAttribute(s.key, s.size)
+		}
+	}
+}
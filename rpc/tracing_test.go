@@ -0,0 +1,65 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/compress"
+	"github.com/uzziahlin/transport/rpc/trace"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := trace.NewInMemoryExporter()
+	tracer := trace.NewTracer(exporter)
+
+	backend := NewEndPoint(":8081", WithServerTracer(tracer))
+	backend.Register(&metaService{name: "backend"})
+
+	constructor := NewProxyConstructor(WithTracer(tracer))
+
+	// frontend没有配置tracer，链路信息仍然会透传给backend
+	frontend := NewEndPoint(":8082")
+	frontend.Register(&metaService{
+		name:   "frontend",
+		caller: constructor.NewProxyCaller(backend),
+	})
+
+	ctx := compress.Context(context.Background(), compress.GZIP)
+
+	_, err := Invoke[UserReq, UserResp](ctx, constructor.NewProxyCaller(frontend), "frontend", "Echo", &UserReq{Id: "tracing"})
+	require.NoError(t, err)
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 3)
+
+	backendServer, backendClient, frontendClient := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, trace.SpanKindClient, frontendClient.Kind)
+	assert.Equal(t, "frontend/Echo", frontendClient.Name)
+	assert.False(t, frontendClient.Parent.IsValid())
+	assert.Equal(t, trace.StatusOK, frontendClient.Status)
+
+	assert.Equal(t, trace.SpanKindClient, backendClient.Kind)
+	assert.Equal(t, frontendClient.SpanContext.TraceID, backendClient.SpanContext.TraceID)
+	assert.Equal(t, frontendClient.SpanContext.SpanID, backendClient.Parent.SpanID)
+
+	assert.Equal(t, trace.SpanKindServer, backendServer.Kind)
+	assert.Equal(t, "backend/Echo", backendServer.Name)
+	assert.Equal(t, backendClient.SpanContext.SpanID, backendServer.Parent.SpanID)
+	assert.True(t, backendServer.Parent.Remote)
+
+	attrs := frontendClient.Attributes
+	assert.Equal(t, "frontend", attrs[trace.AttrService])
+	assert.Equal(t, "Echo", attrs[trace.AttrMethod])
+	assert.Equal(t, 1, attrs[trace.AttrSerializer])
+	assert.Equal(t, int(compress.GZIP), attrs[trace.AttrCompressor])
+	assert.Equal(t, len(`{"Id":"tracing"}`), attrs[trace.AttrRequestSize])
+	assert.NotZero(t, attrs[trace.AttrRequestWireSize])
+	assert.NotZero(t, attrs[trace.AttrResponseSize])
+	assert.NotZero(t, attrs[trace.AttrResponseWireSize])
+
+	assert.Equal(t, len(`{"Id":"tracing"}`), backendServer.Attributes[trace.AttrRequestSize])
+	assert.NotZero(t, backendServer.Attributes[trace.AttrResponseSize])
+}
@@ -0,0 +1,173 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport 定义了服务端如何监听地址以及客户端如何建立连接，
+// 实现该接口可以使用任意的net.Listener以及对应的拨号方式，例如内存连接或者自定义的隧道
+type Transport interface {
+	Listen(addr string) (net.Listener, error)
+	Dial(ctx context.Context, addr string) (net.Conn, error)
+}
+
+// NetTransport 基于net包的Transport，也是默认的Transport，支持以下地址格式：
+//
+//	host:port、tcp://host:port  tcp
+//	unix:///path/to/rpc.sock     unix domain socket
+//	unix://@name                 Linux的抽象命名空间，不会在文件系统中创建socket文件
+type NetTransport struct {
+	// SocketMode unix domain socket文件的权限，为0时由umask决定
+	SocketMode os.FileMode
+}
+
+var defaultTransport = &NetTransport{}
+
+// Listen 监听unix domain socket时，如果socket文件已经存在且没有进程在监听，会先删除该文件
+func (t *NetTransport) Listen(addr string) (net.Listener, error) {
+	network, address := splitAddr(addr)
+
+	if network != "unix" || isAbstract(address) {
+		return net.Listen(network, address)
+	}
+
+	if err := removeStaleSocket(address); err != nil {
+		return nil, err
+	}
+
+	if t.SocketMode == 0 {
+		return net.Listen(network, address)
+	}
+
+	return listenUnixWithMode(address, t.SocketMode)
+}
+
+// listenUnixWithMode 在只有当前用户可以访问的临时目录中创建socket文件，设置好权限之后再链接到path，
+// 避免socket文件在设置权限之前按照umask的权限对其他用户可见。umask是进程级别的，不能在监听时临时修改
+// 使用链接而不是移动，path在检查之后被其他进程创建时返回错误，不会覆盖其他进程的socket文件
+func listenUnixWithMode(path string, mode os.FileMode) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".rpc-sock-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "s")
+
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	ul := l.(*net.UnixListener)
+	// 临时文件随临时目录一起删除，关闭时删除的是path，由unixListener负责
+	ul.SetUnlinkOnClose(false)
+
+	if err = os.Chmod(tmp, mode); err != nil {
+		_ = ul.Close()
+		return nil, err
+	}
+
+	if err = os.Link(tmp, path); err != nil {
+		_ = ul.Close()
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("micro：socket文件 %s 已经存在", path)
+		}
+		return nil, err
+	}
+
+	return &unixListener{UnixListener: ul, path: path}, nil
+}
+
+// unixListener 地址为链接之后的socket文件，关闭时删除socket文件
+type unixListener struct {
+	*net.UnixListener
+	path      string
+	closeOnce sync.Once
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.closeOnce.Do(func() {
+		_ = os.Remove(l.path)
+	})
+	return err
+}
+
+func (t *NetTransport) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	network, address := splitAddr(addr)
+	return dialer.DialContext(ctx, network, address)
+}
+
+// WithTransport 客户端通过t建立连接，与WithDialer相同，后设置的生效
+func WithTransport(t Transport) ConstructorOpt {
+	return func(c *ProxyConstructor) {
+		c.dial = t.Dial
+	}
+}
+
+// WithServerTransport 服务端通过t监听Startup的地址，默认为NetTransport
+func WithServerTransport(t Transport) EndPointOpt {
+	return func(e *EndPoint) {
+		e.transport = t
+	}
+}
+
+// WithSocketPermissions 设置unix domain socket文件的权限，例如0660只允许同组的进程连接
+// 只对默认的NetTransport生效，通过WithServerTransport设置了Transport时需要自行处理
+func WithSocketPermissions(mode os.FileMode) EndPointOpt {
+	return func(e *EndPoint) {
+		e.socketMode = mode
+	}
+}
+
+// splitAddr 将地址拆分为网络类型以及net包使用的地址，没有scheme时为tcp
+func splitAddr(addr string) (network, address string) {
+	scheme, rest, ok := strings.Cut(addr, "://")
+	if !ok {
+		return "tcp", addr
+	}
+	return scheme, rest
+}
+
+func isAbstract(address string) bool {
+	return strings.HasPrefix(address, "@")
+}
+
+// staleSocketDialTimeout 判断socket文件是否有进程在监听时的拨号超时时间
+const staleSocketDialTimeout = 100 * time.Millisecond
+
+// removeStaleSocket 删除进程异常退出后遗留的socket文件，文件不是socket或者仍然有进程在监听时返回错误
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("micro：%s 已经存在且不是socket文件", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, staleSocketDialTimeout); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("micro：%s 已经有进程在监听", path)
+	}
+
+	return os.Remove(path)
+}
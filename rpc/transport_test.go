@@ -0,0 +1,266 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAddr(t *testing.T) {
+	testCases := []struct {
+		addr        string
+		wantNetwork string
+		wantAddress string
+	}{
+		{addr: "localhost:8080", wantNetwork: "tcp", wantAddress: "localhost:8080"},
+		{addr: "tcp://localhost:8080", wantNetwork: "tcp", wantAddress: "localhost:8080"},
+		{addr: "unix:///run/rpc.sock", wantNetwork: "unix", wantAddress: "/run/rpc.sock"},
+		{addr: "unix://rpc.sock", wantNetwork: "unix", wantAddress: "rpc.sock"},
+		{addr: "unix://@rpc", wantNetwork: "unix", wantAddress: "@rpc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			network, address := splitAddr(tc.addr)
+			assert.Equal(t, tc.wantNetwork, network)
+			assert.Equal(t, tc.wantAddress, address)
+		})
+	}
+}
+
+func TestServerName(t *testing.T) {
+	assert.Equal(t, "example.com", serverName("example.com:443"))
+	assert.Equal(t, "example.com", serverName("tcp://example.com:443"))
+	assert.Equal(t, "localhost", serverName("unix:///run/rpc.sock"))
+}
+
+func TestTransport(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		name string
+		addr string
+		opts []EndPointOpt
+		// wantMode 不为0时校验socket文件的权限
+		wantMode os.FileMode
+		linux    bool
+	}{
+		{
+			name: "tcp",
+			addr: "tcp://127.0.0.1:0",
+		},
+		{
+			name: "unix",
+			addr: "unix://" + filepath.Join(dir, "rpc.sock"),
+		},
+		{
+			name:     "socket permissions",
+			addr:     "unix://" + filepath.Join(dir, "perm.sock"),
+			opts:     []EndPointOpt{WithSocketPermissions(0600)},
+			wantMode: 0600,
+		},
+		{
+			name:  "abstract",
+			addr:  fmt.Sprintf("unix://@rpc-test-%d", time.Now().UnixNano()),
+			linux: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.linux && runtime.GOOS != "linux" {
+				t.Skip("抽象命名空间只在Linux上可用")
+			}
+
+			endpoint := NewEndPoint(tc.addr, tc.opts...)
+			endpoint.Register(&UserServiceImpl{})
+
+			l, err := endpoint.server.listen(tc.addr)
+			require.NoError(t, err)
+			go func() {
+				_ = endpoint.Serve(l)
+			}()
+			t.Cleanup(func() {
+				_ = endpoint.Close()
+			})
+
+			addr := l.Addr().String()
+			if network := l.Addr().Network(); network != "tcp" {
+				addr = network + "://" + addr
+			}
+
+			if tc.wantMode != 0 {
+				fi, err := os.Stat(l.Addr().String())
+				require.NoError(t, err)
+				assert.Equal(t, tc.wantMode, fi.Mode().Perm())
+			}
+
+			constructor := NewProxyConstructor()
+			t.Cleanup(func() {
+				_ = constructor.Close()
+			})
+
+			caller := constructor.NewCaller(addr)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			resp, err := Invoke[UserReq, UserResp](ctx, caller, "user-service", "GetById", &UserReq{Id: "1"})
+			require.NoError(t, err)
+			assert.Equal(t, "response: 1", resp.Content)
+		})
+	}
+}
+
+func TestNetTransport_StaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpc.sock")
+	tr := &NetTransport{}
+
+	// 模拟进程异常退出后遗留的socket文件
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, l.Close())
+
+	l, err = tr.Listen("unix://" + path)
+	require.NoError(t, err)
+
+	// 仍然有进程在监听时不能删除
+	_, err = tr.Listen("unix://" + path)
+	assert.Error(t, err)
+	require.NoError(t, l.Close())
+
+	// 不是socket文件时不能删除
+	require.NoError(t, os.WriteFile(path, nil, 0600))
+	_, err = tr.Listen("unix://" + path)
+	assert.Error(t, err)
+}
+
+func TestNetTransport_SocketMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rpc.sock")
+	tr := &NetTransport{SocketMode: 0600}
+
+	l, err := tr.Listen("unix://" + path)
+	require.NoError(t, err)
+	assert.Equal(t, path, l.Addr().String())
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// 创建socket文件使用的临时目录已经删除
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "rpc.sock", entries[0].Name())
+
+	conn, err := tr.Dial(context.Background(), "unix://"+path)
+	require.NoError(t, err)
+	_ = conn.Close()
+
+	// 关闭时删除socket文件
+	require.NoError(t, l.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestListenUnixWithMode_Exists(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rpc.sock")
+
+	// 模拟检查之后其他进程抢先创建了socket文件
+	other, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer func() {
+		_ = other.Close()
+	}()
+
+	_, err = listenUnixWithMode(path, 0600)
+	assert.Error(t, err)
+
+	// 其他进程的socket文件没有被覆盖，临时目录已经删除
+	go func() {
+		conn, err := other.Accept()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	_ = conn.Close()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// pipeTransport 使用net.Pipe的自定义Transport
+type pipeTransport struct {
+	conns chan net.Conn
+}
+
+func (p *pipeTransport) Listen(addr string) (net.Listener, error) {
+	return p, nil
+}
+
+func (p *pipeTransport) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case p.conns <- server:
+		return client, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *pipeTransport) Accept() (net.Conn, error) {
+	conn, ok := <-p.conns
+	if !ok {
+		return nil, net.ErrClosed
+	}
+	return conn, nil
+}
+
+func (p *pipeTransport) Close() error {
+	close(p.conns)
+	return nil
+}
+
+func (p *pipeTransport) Addr() net.Addr {
+	return &net.UnixAddr{Name: "pipe", Net: "pipe"}
+}
+
+func TestCustomTransport(t *testing.T) {
+	tr := &pipeTransport{conns: make(chan net.Conn)}
+
+	endpoint := NewEndPoint("pipe", WithServerTransport(tr))
+	endpoint.Register(&UserServiceImpl{})
+
+	go func() {
+		_ = endpoint.Startup()
+	}()
+	t.Cleanup(func() {
+		_ = endpoint.Close()
+	})
+
+	constructor := NewProxyConstructor(WithTransport(tr))
+	t.Cleanup(func() {
+		_ = constructor.Close()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := Invoke[UserReq, UserResp](ctx, constructor.NewCaller("pipe"), "user-service", "GetById", &UserReq{Id: "1"})
+	require.NoError(t, err)
+	assert.Equal(t, "response: 1", resp.Content)
+}
@@ -0,0 +1,154 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// FieldError 描述服务结构体中某个函数属性不合法的原因
+type FieldError struct {
+	Field  string
+	Type   reflect.Type
+	Reason string
+}
+
+func (f *FieldError) Error() string {
+	return fmt.Sprintf("%s %s: %s", f.Field, f.Type, f.Reason)
+}
+
+// ValidationError 汇总了服务结构体中所有不合法的函数属性
+type ValidationError struct {
+	Service string
+	Fields  []*FieldError
+}
+
+func (v *ValidationError) Error() string {
+	sb := strings.Builder{}
+
+	sb.WriteString(fmt.Sprintf("micro：服务 %s 存在 %d 个不合法的函数属性", v.Service, len(v.Fields)))
+
+	for _, f := range v.Fields {
+		sb.WriteString("\n\t")
+		sb.WriteString(f.Error())
+	}
+
+	return sb.String()
+}
+
+// validateService 校验服务必须是非nil的一级结构体指针，并校验所有可导出的函数属性以及其rpc tag
+// 返回以属性名为key的方法选项，被rpc:"-"忽略的属性不会出现在结果中
+func (p *ProxyConstructor) validateService(service Service) (map[string]*methodOptions, error) {
+	if service == nil {
+		return nil, errors.New("micro：入参不能为nil")
+	}
+
+	val := reflect.ValueOf(service)
+
+	if val.Kind() == reflect.Pointer && val.IsNil() {
+		return nil, errors.New("micro：入参不能为nil")
+	}
+
+	if val.Kind() != reflect.Pointer || val.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("micro：入参必须为结构体指针且为一级指针, 实际类型为 %s", val.Type())
+	}
+
+	typ := val.Type().Elem()
+
+	var fields []*FieldError
+
+	methods := make(map[string]*methodOptions, typ.NumField())
+
+	for i := 0; i < typ.NumField(); i++ {
+		fd := typ.Field(i)
+
+		// 不可导出的属性无法篡改，直接忽略
+		if fd.Type.Kind() != reflect.Func || !fd.IsExported() {
+			continue
+		}
+
+		tag, ok := fd.Tag.Lookup("rpc")
+
+		if ok && tag == "-" {
+			continue
+		}
+
+		reason := validateFunc(fd.Type)
+
+		if reason == "" {
+			var opts *methodOptions
+			opts, reason = p.parseMethodTag(fd.Name, tag)
+			methods[fd.Name] = opts
+		}
+
+		if reason != "" {
+			fields = append(fields, &FieldError{
+				Field:  fd.Name,
+				Type:   fd.Type,
+				Reason: reason,
+			})
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, &ValidationError{
+			Service: typ.String(),
+			Fields:  fields,
+		}
+	}
+
+	return methods, nil
+}
+
+// validateFunc 校验函数签名，要求形如 func(context.Context, *Req) (*Resp, error)
+// 返回不合法的原因，合法则返回空字符串
+func validateFunc(typ reflect.Type) string {
+	for i := 0; i < typ.NumIn(); i++ {
+		if typ.In(i).Kind() == reflect.Chan {
+			return "不支持流式调用，参数不能为channel"
+		}
+	}
+
+	for i := 0; i < typ.NumOut(); i++ {
+		if typ.Out(i).Kind() == reflect.Chan {
+			return "不支持流式调用，返回值不能为channel"
+		}
+	}
+
+	if typ.IsVariadic() {
+		return "不支持可变参数"
+	}
+
+	if typ.NumIn() != 2 {
+		return fmt.Sprintf("必须有且只有两个参数, 实际有 %d 个", typ.NumIn())
+	}
+
+	if typ.In(0) != contextType {
+		return fmt.Sprintf("第一个参数必须是context.Context, 实际为 %s", typ.In(0))
+	}
+
+	if typ.In(1).Kind() != reflect.Pointer {
+		return fmt.Sprintf("第二个参数必须是指针, 实际为 %s", typ.In(1))
+	}
+
+	if typ.NumOut() != 2 {
+		return fmt.Sprintf("必须有且只有两个返回值, 实际有 %d 个", typ.NumOut())
+	}
+
+	if typ.Out(0).Kind() != reflect.Pointer {
+		return fmt.Sprintf("第一个返回值必须是指针, 实际为 %s", typ.Out(0))
+	}
+
+	if typ.Out(1) != errorType {
+		return fmt.Sprintf("第二个返回值必须是error, 实际为 %s", typ.Out(1))
+	}
+
+	return ""
+}
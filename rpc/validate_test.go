@@ -0,0 +1,100 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyConstructor_InitProxyValidate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		service    Service
+		wantErr    string
+		wantFields []string
+	}{
+		{
+			name:    "nil",
+			service: nil,
+			wantErr: "micro：入参不能为nil",
+		},
+		{
+			name:    "nil pointer",
+			service: (*UserService)(nil),
+			wantErr: "micro：入参不能为nil",
+		},
+		{
+			name:    "not pointer",
+			service: UserService{},
+			wantErr: "micro：入参必须为结构体指针且为一级指针, 实际类型为 rpc.UserService",
+		},
+		{
+			name:    "valid",
+			service: &UserService{},
+		},
+		{
+			name:       "invalid fields",
+			service:    &invalidService{},
+			wantFields: []string{"NoCtx", "NoPtrReq", "OneResult", "NoErr", "Variadic", "Stream"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewProxyConstructor().InitProxy(tc.service)
+
+			if tc.wantFields != nil {
+				var vErr *ValidationError
+				require.ErrorAs(t, err, &vErr)
+
+				fields := make([]string, 0, len(vErr.Fields))
+				for _, f := range vErr.Fields {
+					fields = append(fields, f.Field)
+				}
+				assert.Equal(t, tc.wantFields, fields)
+				return
+			}
+
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestProxyConstructor_MustInitProxy(t *testing.T) {
+	constructor := NewProxyConstructor()
+
+	assert.NotPanics(t, func() {
+		constructor.MustInitProxy(&UserService{})
+	})
+
+	assert.Panics(t, func() {
+		constructor.MustInitProxy(&invalidService{})
+	})
+}
+
+type invalidService struct {
+	NoCtx     func(req *UserReq) (*UserResp, error)
+	NoPtrReq  func(ctx context.Context, req UserReq) (*UserResp, error)
+	OneResult func(ctx context.Context, req *UserReq) error
+	NoErr     func(ctx context.Context, req *UserReq) (*UserResp, string)
+	Variadic  func(ctx context.Context, req ...*UserReq) (*UserResp, error)
+	Stream    func(ctx context.Context, req *UserReq) (chan *UserResp, error)
+
+	Valid func(ctx context.Context, req *UserReq) (*UserResp, error)
+
+	// 不可导出的属性会被忽略
+	helper func()
+}
+
+func (i *invalidService) Info() ServiceInfo {
+	return ServiceInfo{
+		ServiceName: "invalid-service",
+	}
+}
@@ -0,0 +1,204 @@
+package rpc
+
+import (
+	"fmt"
+	"github.com/uzziahlin/transport/rpc/semver"
+	"github.com/uzziahlin/transport/rpc/status"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// 请求中携带客户端期望的版本范围以及分组，响应中携带实际处理请求的服务版本
+const (
+	versionKey = "sys_version"
+	groupKey   = "sys_group"
+)
+
+// VersionMismatchError 没有与期望的版本范围以及分组匹配的服务
+// 服务端找不到匹配的服务，或者客户端发现服务端返回的版本不在范围内时返回，传递给客户端时转换为status.NotFound
+type VersionMismatchError struct {
+	Service string
+	Group   string
+	// Version 期望的版本范围
+	Version string
+	// Available 可用的服务，格式为 分组/版本，没有分组时只有版本
+	Available []string
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("micro：服务 %q 没有与分组 %q 版本 %q 匹配的实例, 可用的实例为 [%s]",
+		e.Service, e.Group, e.Version, strings.Join(e.Available, ", "))
+}
+
+func (e *VersionMismatchError) Status() *status.Status {
+	return status.New(status.NotFound, e.Error()).WithDetails(e.Available...)
+}
+
+// variantName 服务实例的描述，格式为 分组/版本
+func variantName(group, version string) string {
+	if version == "" {
+		version = "unversioned"
+	}
+	if group == "" {
+		return version
+	}
+	return group + "/" + version
+}
+
+// newServiceStub 解析服务的方法表以及版本号，版本号或者方法选项不合法时返回错误
+func newServiceStub(service Service) (*reflectionStub, error) {
+	info := service.Info()
+
+	stub := &reflectionStub{
+		s:     service,
+		info:  info,
+		value: reflect.ValueOf(service),
+	}
+
+	stub.methods, stub.methodNames, stub.rejected = newMethodTable(stub.value)
+
+	if o, ok := service.(MethodOptioner); ok {
+		if err := applyMethodOptions(info.ServiceName, stub.methods, o.MethodOptions()); err != nil {
+			return nil, err
+		}
+	}
+
+	if info.Version != "" {
+		v, err := semver.Parse(info.Version)
+		if err != nil {
+			return nil, fmt.Errorf("micro：服务 %q 的版本号不合法, %w", info.ServiceName, err)
+		}
+		stub.version = &v
+	}
+
+	return stub, nil
+}
+
+// sameVariant 分组相同且版本号相等，例如v1.2.0与1.2.0是同一个版本
+func (r *reflectionStub) sameVariant(o *reflectionStub) bool {
+	if r.info.Group != o.info.Group {
+		return false
+	}
+	if r.version == nil || o.version == nil {
+		return r.version == nil && o.version == nil
+	}
+	return r.version.Compare(*o.version) == 0
+}
+
+// resolveLocked 从服务的所有实例中选出分组相同且版本在范围内的最高版本，调用方需要持有servicesMu
+// 版本范围为空时匹配任意版本，包括没有版本号的实例，否则只匹配有版本号的实例
+func (e *EndPoint) resolveLocked(name, group, version string) (*reflectionStub, error) {
+	stubs, ok := e.services[name]
+
+	if !ok {
+		return nil, status.Newf(status.Unimplemented, "micro：服务 %q 不存在", name).
+			WithDetails(e.serviceNamesLocked()...)
+	}
+
+	// 大部分请求不指定版本范围，跳过解析
+	var rng semver.Range
+	if version != "" {
+		var err error
+		if rng, err = e.ranges.parse(version); err != nil {
+			return nil, status.Newf(status.InvalidArgument, "micro：版本范围 %q 格式不对, %v", version, err)
+		}
+	}
+
+	var res *reflectionStub
+
+	for _, stub := range stubs {
+		if stub.info.Group != group {
+			continue
+		}
+
+		if !rng.IsAny() && (stub.version == nil || !rng.Match(*stub.version)) {
+			continue
+		}
+
+		if res == nil || stub.newerThan(res) {
+			res = stub
+		}
+	}
+
+	if res == nil {
+		available := make([]string, 0, len(stubs))
+		for _, stub := range stubs {
+			available = append(available, variantName(stub.info.Group, stub.info.Version))
+		}
+		return nil, &VersionMismatchError{
+			Service:   name,
+			Group:     group,
+			Version:   version,
+			Available: available,
+		}
+	}
+
+	return res, nil
+}
+
+// newerThan 没有版本号的实例低于所有有版本号的实例
+func (r *reflectionStub) newerThan(o *reflectionStub) bool {
+	if r.version == nil {
+		return false
+	}
+	return o.version == nil || r.version.Compare(*o.version) > 0
+}
+
+// checkVersion 客户端校验服务端返回的版本是否在期望的范围内，避免不支持版本路由的服务端忽略版本范围
+func checkVersion(serviceName string, opts *methodOptions, got string) error {
+	if opts.versionRange.IsAny() {
+		return nil
+	}
+
+	if v, err := semver.Parse(got); err == nil && opts.versionRange.Match(v) {
+		return nil
+	}
+
+	return &VersionMismatchError{
+		Service:   serviceName,
+		Group:     opts.group,
+		Version:   opts.version,
+		Available: []string{variantName(opts.group, got)},
+	}
+}
+
+// maxCachedRanges 缓存的版本范围的数量上限，版本范围来自客户端，需要限制缓存的大小
+const maxCachedRanges = 256
+
+// rangeCache 缓存解析之后的版本范围，同一个客户端通常使用固定的版本范围，避免每个请求都重新解析
+// 缓存已满时不再缓存新的版本范围，只缓存解析成功的版本范围
+type rangeCache struct {
+	mu     sync.RWMutex
+	ranges map[string]semver.Range
+	max    int
+}
+
+func newRangeCache(max int) *rangeCache {
+	return &rangeCache{
+		ranges: make(map[string]semver.Range, 16),
+		max:    max,
+	}
+}
+
+func (c *rangeCache) parse(s string) (semver.Range, error) {
+	c.mu.RLock()
+	rng, ok := c.ranges[s]
+	c.mu.RUnlock()
+	if ok {
+		return rng, nil
+	}
+
+	rng, err := semver.ParseRange(s)
+	if err != nil {
+		return rng, err
+	}
+
+	c.mu.Lock()
+	if len(c.ranges) < c.max {
+		c.ranges[s] = rng
+	}
+	c.mu.Unlock()
+
+	return rng, nil
+}
@@ -0,0 +1,204 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/transport/rpc/message"
+	"github.com/uzziahlin/transport/rpc/semver"
+	"github.com/uzziahlin/transport/rpc/status"
+)
+
+// versionedService 返回自身的分组以及版本号
+type versionedService struct {
+	info ServiceInfo
+}
+
+func (s *versionedService) GetById(ctx context.Context, req *UserReq) (*UserResp, error) {
+	return &UserResp{Content: variantName(s.info.Group, s.info.Version)}, nil
+}
+
+func (s *versionedService) Info() ServiceInfo {
+	return s.info
+}
+
+// versionedClient 使用指定分组以及版本范围的客户端
+type versionedClient struct {
+	info    ServiceInfo
+	GetById func(ctx context.Context, req *UserReq) (*UserResp, error)
+}
+
+func (c *versionedClient) Info() ServiceInfo {
+	return c.info
+}
+
+func TestEndPoint_Version(t *testing.T) {
+	endpoint := NewEndPoint("")
+	for _, info := range []ServiceInfo{
+		{ServiceName: "user-service"},
+		{ServiceName: "user-service", Version: "1.0.0"},
+		{ServiceName: "user-service", Version: "v1.2.0"},
+		{ServiceName: "user-service", Version: "2.0.0"},
+		{ServiceName: "user-service", Version: "1.5.0", Group: "blue"},
+	} {
+		endpoint.Register(&versionedService{info: info})
+	}
+
+	constructor := NewProxyConstructor(WithDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go endpoint.handler(server)
+		return client, nil
+	}))
+	t.Cleanup(func() {
+		_ = constructor.Close()
+	})
+
+	available := []string{"unversioned", "1.0.0", "v1.2.0", "2.0.0", "blue/1.5.0"}
+
+	testCases := []struct {
+		name    string
+		group   string
+		version string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "any version",
+			want: "2.0.0",
+		},
+		{
+			name:    "caret",
+			version: "^1.0",
+			want:    "v1.2.0",
+		},
+		{
+			name:    "exact",
+			version: "1.0.0",
+			want:    "1.0.0",
+		},
+		{
+			name:    "group",
+			group:   "blue",
+			version: "1.x",
+			want:    "blue/1.5.0",
+		},
+		{
+			name:    "no matching version",
+			version: "^3",
+			wantErr: (&VersionMismatchError{
+				Service:   "user-service",
+				Version:   "^3",
+				Available: available,
+			}).Status(),
+		},
+		{
+			name:  "no matching group",
+			group: "green",
+			wantErr: (&VersionMismatchError{
+				Service:   "user-service",
+				Group:     "green",
+				Available: available,
+			}).Status(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &versionedClient{info: ServiceInfo{
+				ServiceName: "user-service",
+				Version:     tc.version,
+				Group:       tc.group,
+			}}
+			require.NoError(t, constructor.InitProxy(client))
+
+			resp, err := client.GetById(context.Background(), &UserReq{Id: "1"})
+			if tc.wantErr != nil {
+				assert.Equal(t, tc.wantErr, err)
+				assert.Equal(t, status.NotFound, status.CodeOf(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, resp.Content)
+		})
+	}
+}
+
+func TestEndPoint_RegisterInvalidVersion(t *testing.T) {
+	endpoint := NewEndPoint("")
+
+	err := endpoint.Register(&versionedService{info: ServiceInfo{ServiceName: "user-service", Version: "1.2"}})
+	assert.Error(t, err)
+
+	// 注册失败的服务不会出现在服务表中
+	assert.Equal(t, HealthServiceUnknown, endpoint.ServingStatus("user-service"))
+	assert.NotContains(t, endpoint.serviceNames(), "user-service")
+}
+
+func TestRangeCache(t *testing.T) {
+	c := newRangeCache(2)
+
+	rng, err := c.parse("^1.2")
+	require.NoError(t, err)
+	assert.True(t, rng.Match(semver.MustParse("1.3.0")))
+
+	// 解析失败的版本范围不会被缓存
+	_, err = c.parse(">=a")
+	assert.Error(t, err)
+	assert.Len(t, c.ranges, 1)
+
+	_, err = c.parse("~2.0")
+	require.NoError(t, err)
+
+	// 缓存已满时仍然可以解析，但是不再缓存
+	rng, err = c.parse("3.x")
+	require.NoError(t, err)
+	assert.True(t, rng.Match(semver.MustParse("3.1.0")))
+	assert.Len(t, c.ranges, 2)
+	assert.NotContains(t, c.ranges, "3.x")
+}
+
+func TestProxyConstructor_InvalidVersionRange(t *testing.T) {
+	client := &versionedClient{info: ServiceInfo{ServiceName: "user-service", Version: ">=a"}}
+	assert.Error(t, NewProxyConstructor().InitProxy(client))
+}
+
+// unversionedProxy 模拟不支持版本路由的服务端，响应中没有版本号
+type unversionedProxy struct{}
+
+func (p *unversionedProxy) Invoke(ctx context.Context, req *message.Request) (*message.Response, error) {
+	return &message.Response{Data: []byte(`{"Content":"ok"}`)}, nil
+}
+
+func TestProxyConstructor_CheckVersion(t *testing.T) {
+	testCases := []struct {
+		name    string
+		version string
+		wantErr error
+	}{
+		{
+			name: "any version",
+		},
+		{
+			name:    "server ignores version",
+			version: "^2",
+			wantErr: &VersionMismatchError{
+				Service:   "user-service",
+				Version:   "^2",
+				Available: []string{"unversioned"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &versionedClient{info: ServiceInfo{ServiceName: "user-service", Version: tc.version}}
+			require.NoError(t, NewProxyConstructor().setFuncField(client, &unversionedProxy{}))
+
+			_, err := client.GetById(context.Background(), &UserReq{Id: "1"})
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
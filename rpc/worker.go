@@ -0,0 +1,53 @@
+package rpc
+
+import "sync"
+
+// workerPool 限制同时执行的任务数量，任务数达到上限时Go会阻塞，直到有任务结束
+type workerPool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{
+		sem: make(chan struct{}, size),
+	}
+}
+
+// Go 在新的g中执行fn
+func (w *workerPool) Go(fn func()) {
+	w.sem <- struct{}{}
+	w.wg.Add(1)
+
+	go func() {
+		defer func() {
+			<-w.sem
+			w.wg.Done()
+		}()
+		fn()
+	}()
+}
+
+// TryGo 与Go相同，但是任务数达到上限时不会阻塞，直接返回false
+func (w *workerPool) TryGo(fn func()) bool {
+	select {
+	case w.sem <- struct{}{}:
+	default:
+		return false
+	}
+	w.wg.Add(1)
+
+	go func() {
+		defer func() {
+			<-w.sem
+			w.wg.Done()
+		}()
+		fn()
+	}()
+	return true
+}
+
+// Wait 等待所有已经提交的任务结束
+func (w *workerPool) Wait() {
+	w.wg.Wait()
+}
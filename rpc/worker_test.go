@@ -0,0 +1,34 @@
+package rpc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool(t *testing.T) {
+	pool := newWorkerPool(2)
+
+	var running, max int32
+
+	for i := 0; i < 10; i++ {
+		pool.Go(func() {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+	}
+
+	pool.Wait()
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&running))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&max))
+}